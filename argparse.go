@@ -2,10 +2,16 @@
 package argparse
 
 import (
-	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
 const DisableDescription = "DISABLEDDESCRIPTIONWILLNOTSHOWUP"
@@ -20,13 +26,134 @@ type Command struct {
 	commands    []*Command
 	parsed      bool
 	parent      *Command
+	parser      *Parser
+	groups      []*FlagGroup
+	aliases     []string
+	ignored     []error
+	notes       []note
+}
+
+// note is a line of text shown in Usage among arguments, right after the argument it follows
+type note struct {
+	after *arg
+	text  string
 }
 
 // Parser is a top level object of argparse. It MUST NOT ever be created manually. Instead one should use
 // argparse.NewParser() method that will create new parser, propagate necessary private fields and call needed
 // functions.
+//
+// Parser.DisableHelpExit - when set, "-h|--help" on command line will not print usage and exit the program,
+// instead Parse will return ErrHelpRequested leaving it to caller what to do next. Usage text still can be
+// retrieved with Usage method. Likewise version is not printed and ErrVersionRequested is returned.
+//
+// Parser.ExitFunc - function called with exit code 0 once usage was printed for "-h|--help". Defaults to os.Exit,
+// can be replaced to run custom shutdown or to keep the program running in tests. If it returns, Parse stops
+// and returns ErrHelpRequested.
+//
+// Parser.ErrorPositions - when set, errors of named arguments tell position of the argument on command line,
+// such as "[-c|--count] must be an integer, got \"x\" at argument position 4". Position is an index in the list
+// passed to Parse, where program name is at 0.
+//
+// Parser.AllowAbbreviations - when set, long names may be shortened to any unambiguous prefix, such as
+// "--verb" for "--verbose". Exact long name always takes precedence over prefix of another one.
+//
+// Parser.CollectErrors - when set, Parse carries on past arguments that failed and returns all errors at once,
+// each on a separate line of the message. Arguments that failed are not checked for being required and do
+// not get their Default value. By default Parse stops at the first error.
+//
+// Parser.HelpShort, Parser.HelpLong - names of the argument that prints usage, "h" and "help" by default. Short
+// name can be set to empty string to leave only the long one, while long name cannot be empty. Help is the first
+// argument of Parser, so any other argument with the same name is ignored as a duplicate. Set these right after
+// NewParser, before adding arguments, to free "-h" for another argument.
+//
+// Parser.VersionShort, Parser.VersionLong - names of the argument added by SetVersion, "V" and "version" by
+// default. Short name can be set to empty string to leave only the long one. Version argument follows help,
+// so set these before SetVersion and call SetVersion before adding arguments, same as for help.
+//
+// Parser.DisableHelp - removes the argument that prints usage, so that neither "-h" nor "--help" are treated
+// specially. Set it right after NewParser, before adding arguments that use these names.
+//
+// Parser.PassThroughUnknown - when set, arguments that look like names but match no argument are not treated as
+// error and can be retrieved with Unknown method instead. Useful for passing them to a wrapped program. Values
+// of such arguments are only passed through when attached with "=", such as "--foo=bar".
+//
+// Parser.DisableInheritance - by default arguments of a command can be used with all of its sub-commands, such as
+// global flags of Parser. When set, arguments are only taken from CLI for the command that was invoked, and its
+// Usage lists only its own arguments. Arguments of preceding commands still get their Default values. Help is
+// always available.
+//
+// Parser.PrintUsageOnError - when set, Parse writes error it is about to return to stderr, or to writer set with
+// SetOutput, followed by Usage of the command named on CLI, same as Usage(err) would return. Help request is not
+// an error and is not printed.
+//
+// Parser.OnUnknown - function called for every argument that looks like a name but matches no argument, such as
+// "--colour". Returning nil drops the argument, returning error stops Parse with that error. Value of unknown
+// argument is only passed along when attached with "=", such as "--colour=red". Takes precedence over
+// PassThroughUnknown.
+//
+// Parser.Strict - when set, argument that is left over right after the value of a named argument is reported
+// as unexpected value of that argument, such as "[--name] takes one value, but [b] follows it" for "--name a b",
+// instead of generic "too many arguments". Values taken by positional arguments are never left over.
+//
+// Parser.HelpFunc - function that renders help message instead of the built-in one, for "-h|--help" as well as
+// Usage calls. It gets Command that help is for, which is the embedded Command of Parser for the program itself,
+// and can describe it with Name, Description, Arguments and Commands. Message passed to Usage, such as error,
+// is still written before the output of HelpFunc. Use DefaultUsage to get the built-in help message.
+//
+// Parser.WarningOutput - writer that warnings about deprecated arguments are written to, os.Stderr if nil.
+//
+// Parser.Messages - translations of error messages, keyed by the English format they replace, such as
+// "[%s] is required" or "[%s] must be followed by an integer". Translation must take the same verbs in the same
+// order, the first of which is usually the name of the argument. Messages that have no translation are left
+// in English. Errors returned by Options.Validate and similar functions are passed on as they are.
+//
+// Parser.Epilog - text that is appended to Usage output of Parser after the list of arguments. Useful for notes
+// and examples that are not specific to any argument.
 type Parser struct {
 	Command
+	DisableHelpExit    bool
+	ExitFunc           func(code int)
+	ErrorPositions     bool
+	AllowAbbreviations bool
+	CollectErrors      bool
+	HelpShort          string
+	HelpLong           string
+	VersionShort       string
+	VersionLong        string
+	DisableHelp        bool
+	PassThroughUnknown bool
+	DisableInheritance bool
+	PrintUsageOnError  bool
+	OnUnknown          func(flag string) error
+	Strict             bool
+	HelpFunc           func(cmd *Command) string
+	WarningOutput      io.Writer
+	Messages           map[string]string
+	Epilog             string
+	width              int
+	remaining          []string
+	errors             []error
+	helpArg            *arg
+	versionArg         *arg
+	version            string
+	unknown            []string
+	responsePrefix     byte
+	shortPrefix        string
+	longPrefix         string
+	onParse            []parseEvent
+	output             io.Writer
+	succeeded          bool
+	values             map[int]*arg
+	size               int
+	report             *Report
+}
+
+// parseEvent is an argument that got its value, waiting for its Options.OnParse to be called
+type parseEvent struct {
+	arg      *arg
+	value    string
+	position int
 }
 
 // Options are specific options for every argument. They can be provided if necessary.
@@ -40,17 +167,149 @@ type Parser struct {
 // (e.g. as String does), then these are provided as args to function. If validation fails the error must be returned,
 // which will be the output of `Parser.Parse` method.
 //
+// Options.Validators - additional validation functions that work same as Validate. They are executed in order
+// after Validate and the first error is returned as is, skipping the rest.
+//
 // Options.Help - A help message to be displayed in Usage output. Can be of any length as the message will be
 // formatted to fit max screen width of 100 characters.
 //
 // Options.Default - A default value for an argument. This value will be assigned to the argument at the end of parsing
 // in case if this argument was not supplied on command line. File default value is a string which it will be open with
-// provided options. In case if provided value type does not match expected, the error will be returned on run-time.
+// provided options. Regexp default value is a string which will be compiled.
+// URL default value is a string which will be parsed. In case if provided value type does not match expected, the error will be returned on run-time.
+//
+// Options.Negatable - allows Flag to be explicitly set to false with "--no-<long name>" form. Useful when Default
+// is true. Short name never has a negated form as it would be ambiguous with combined shorthand flags.
+// List, IntList, FloatList and StringMap are cleared by the negated form instead, dropping their Default and
+// values provided before it, while values provided after it are collected as usual. So that
+// "--include a --no-include --include b" results in [b]. Other types cannot be negated, which Validate reports.
+//
+// Options.EnvVar - name of environment variable to take the value from when argument was not supplied on command
+// line. Non-empty value is processed in exactly same way as command line value would be, including Validate and
+// Selector checks. Command line takes precedence over environment variable, which takes precedence over Default.
+//
+// Options.CaseInsensitive - makes Selector match provided value ignoring case. The resulting value is always
+// the one from the list of allowed options.
+//
+// Options.Hidden - leaves argument out of Usage output while it still can be used on CLI. Useful for experimental
+// or internal arguments. Hidden argument cannot be Required since users would have no way to find out about it,
+// setting both is a programming error and will panic when argument is created.
+//
+// Options.RequiredIf - makes argument required only when another argument was provided. Takes pointer
+// returned when that argument was created, so it must be created before this one.
+//
+// Options.Examples - example usages of an argument that are listed under its help message in Usage output.
+//
+// Options.Min, Options.Max - inclusive bounds for numeric arguments (Int, Uint, Int64, Bytes and Float). Either can be omitted.
+// For IntList and FloatList bounds apply to every element.
+// Setting Min greater than Max is a programming error and will panic when argument is created.
+//
+// Options.MinOccurrences, Options.MaxOccurrences - bounds for number of times argument that can be repeated,
+// such as List, may be provided. Zero means no bound. MinOccurrences of 1 or more makes argument required.
+//
+// Options.Group - name of the section that argument is listed under in Usage output, such as "Networking".
+// Sections follow in order of their first argument, arguments without a group are listed under "Arguments".
+// Has no effect on parsing.
+//
+// Options.Separator - lets List, IntList, FloatList and StringMap take several elements in a single value, such as "--tag a,b,c"
+// with Separator ",". Repeating the argument still appends to the same list.
+//
+// Options.SkipEmpty - drops empty elements produced by Separator, so that "a,,b" results in two elements.
+//
+// Options.UniqueKeys - makes StringMap fail when the same key is provided more than once instead of overwriting its value.
+//
+// Options.Unique - makes List, IntList, FloatList and PositionalList fail when the same value is provided more
+// than once. The error names the duplicate value.
+//
+// Options.DropDuplicates - makes same lists silently skip values that were already provided instead of failing.
+//
+// Options.Metavar - placeholder of the value shown in Usage output instead of the one based on type, such as "N"
+// to show "--count N" rather than "--count <integer>". For Selector it replaces the list of allowed values.
+//
+// Options.RequireEquals - makes argument accept its value only in "--name=value" form, so that the value never
+// comes as a separate command line argument. Space separated and attached short forms are rejected.
+//
+// Options.Base - base in which Int, Uint, Int64 and IntList values are parsed, from 2 to 36. By default values
+// are decimal unless they start with "0x", "0o" or "0b" prefix. Set it to 10 to reject hexadecimal and other forms.
+//
+// Options.OnParse - function called with the value of argument after it was assigned, or with empty string
+// for flags given without value. Calls happen once all arguments got their values, in order arguments appear
+// on command line, so that "--config" given first is handled first. Values of positional arguments and values
+// from environment variables come after those. For File arguments value is the path of opened file.
+// Returned error becomes the parse error.
+//
+// Options.AllowFileValue - makes value that starts with "@" be read from the file it names, such as
+// "--token @/run/secrets/token", so that secrets do not show up in shell history or process list. Contents
+// of the file are trimmed of surrounding white space and checked the same way as value typed on command line.
+// Value that starts with "@" itself is written as "@@", such as "@@home" for "@home". Response files
+// enabled with "@" prefix are expanded first, so a different prefix should be used for them.
+//
+// Options.Deprecated - message shown when argument is provided, such as "use --new instead". Argument works as
+// usual, and warning "--name is deprecated, message" is written to Parser.WarningOutput once per Parse. Combine
+// it with Hidden to leave the argument out of Usage.
+//
+// Options.RejectFlagLikeValues - makes argument fail when the value following it is a name of another argument,
+// as in "--output --verbose", which likely means the value was forgotten. Values attached with "=" are accepted.
+//
+// Options.ConsumeRaw - makes argument take the following argument as its value as is, even if it looks like a name
+// of another argument, "-h" or "--", such as "-e -n" for sed-like expression. It is the opposite of
+// RejectFlagLikeValues, and the two cannot be used together.
+//
+// Options.Aliases, Options.ShortAliases - other long names and single character short names that argument is
+// also known by, such as "colour" for "color". They work everywhere the names do, including combined shorthand
+// flags and negated form, but only the names are shown in Usage, with aliases noted after help message. Argument
+// whose name or alias is already used by another one is ignored, same as duplicate arguments.
+//
+// Options.MkdirAll - makes File and FileList create missing parent directories of the file before opening it,
+// which is useful together with os.O_CREATE for output files. Directories are created with DirPerm permissions,
+// or 0755 if it is not set.
+//
+// Options.AllowStdStreams - makes File take "-" for a standard stream instead of a file with that name:
+// os.Stdout if the file is opened with os.O_WRONLY, os.Stdin otherwise. Such result shares descriptor with
+// the standard stream and must not be closed by the caller.
+//
+// Options.Schemes - URL schemes that URL argument accepts, such as "https". Any scheme is accepted if empty.
+//
+// Options.ErrorFormatter - builds error returned when value of Selector or Choice is not one of allowed values.
+// It gets name of argument as shown in error messages, such as "-l|--level", and the list of allowed values.
+// The error is returned by `Parser.Parse` as is.
 type Options struct {
-	Required bool
-	Validate func(args []string) error
-	Help     string
-	Default  interface{}
+	Required             bool
+	Validate             func(args []string) error
+	Validators           []func(args []string) error
+	Help                 string
+	Default              interface{}
+	Negatable            bool
+	EnvVar               string
+	CaseInsensitive      bool
+	Hidden               bool
+	RequiredIf           interface{}
+	Examples             []string
+	Min                  *float64
+	Max                  *float64
+	Separator            string
+	SkipEmpty            bool
+	Group                string
+	MinOccurrences       int
+	MaxOccurrences       int
+	UniqueKeys           bool
+	Unique               bool
+	DropDuplicates       bool
+	ErrorFormatter       func(name string, allowed []string) error
+	Schemes              []string
+	Metavar              string
+	RequireEquals        bool
+	Base                 int
+	OnParse              func(value string) error
+	Deprecated           string
+	RejectFlagLikeValues bool
+	Aliases              []string
+	ShortAliases         []string
+	MkdirAll             bool
+	DirPerm              os.FileMode
+	AllowStdStreams      bool
+	AllowFileValue       bool
+	ConsumeRaw           bool
 }
 
 // NewParser creates new Parser object that will allow to add arguments for parsing
@@ -61,6 +320,14 @@ func NewParser(name string, description string) *Parser {
 
 	p.name = name
 	p.description = description
+	p.parser = p
+	p.ExitFunc = os.Exit
+	p.HelpShort = "h"
+	p.HelpLong = "help"
+	p.VersionShort = "V"
+	p.VersionLong = "version"
+	p.shortPrefix = "-"
+	p.longPrefix = "--"
 
 	p.args = make([]*arg, 0)
 	p.commands = make([]*Command, 0)
@@ -77,15 +344,23 @@ func NewParser(name string, description string) *Parser {
 // All commands are considered as required and all commands can have their own argument set.
 // Commands are processed Parser -> Command -> sub-Command.
 // Arguments will be processed in order of sub-Command -> Command -> Parser.
+// Description is shown in Usage of the command, while list of commands in Usage of its parent shows
+// only the first line of it.
+// Name must differ from names and aliases of all other commands on the same level,
+// colliding name is a programming error and will panic when command is created.
 func (o *Command) NewCommand(name string, description string) *Command {
+	for _, v := range o.commands {
+		if v.matches(name) {
+			panic(fmt.Sprintf("argparse: [%s] command collides with [%s] command", name, v.name))
+		}
+	}
+
 	c := new(Command)
 	c.name = name
 	c.description = description
 	c.parsed = false
 	c.parent = o
 
-	c.help()
-
 	if o.commands == nil {
 		o.commands = make([]*Command, 0)
 	}
@@ -95,13 +370,30 @@ func (o *Command) NewCommand(name string, description string) *Command {
 	return c
 }
 
+// AddAlias adds alternative name this Command can be invoked by, such as "rm" for "remove".
+// Alias must differ from names and aliases of all other commands on the same level,
+// colliding alias is a programming error and will panic when added.
+func (o *Command) AddAlias(alias string) {
+	if o.parent == nil {
+		panic(fmt.Sprintf("argparse: [%s] is not a command and cannot have aliases", o.name))
+	}
+	for _, v := range o.parent.commands {
+		if v.matches(alias) {
+			panic(fmt.Sprintf("argparse: alias [%s] of [%s] command collides with [%s] command", alias, o.name, v.name))
+		}
+	}
+	o.aliases = append(o.aliases, alias)
+}
+
 // Flag Creates new flag type of argument, which is boolean value showing if argument was provided or not.
 // Takes short name, long name and pointer to options (optional).
 // Short name must be single character, but can be omitted by giving empty string.
 // Long name is required.
 // Returns pointer to boolean with starting value `false`. If Parser finds the flag
 // provided on Command line arguments, then the value is changed to true.
-// Only for Flag shorthand arguments can be combined together such as `rm -rf`
+// Value can also be given explicitly as in `--flag=false`, which accepts same values as strconv.ParseBool.
+// With Default of true the flag is true unless set to false this way or with the negated form of Negatable flag.
+// Only for Flag shorthand arguments can be combined together such as `rm -rf`, combined ones cannot take a value
 func (o *Command) Flag(short string, long string, opts *Options) *bool {
 	var result bool
 
@@ -119,6 +411,106 @@ func (o *Command) Flag(short string, long string, opts *Options) *bool {
 	return &result
 }
 
+// BoolPtr creates new flag that tells whether it was provided on CLI at all. It works as Flag, but returns
+// pointer to nil pointer, which is set to point to true or false only once the flag is provided, such as
+// `--force`, `--force=false` or negated `--no-force` of Negatable flag. Useful for merging CLI over config,
+// where nil means there is nothing to override. Default, if set, must be a bool.
+func (o *Command) BoolPtr(short string, long string, opts *Options) **bool {
+	var result *bool
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   1,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// FlagCounter creates new flag counter argument, which counts how many times it was provided on CLI.
+// Takes short name, long name and pointer to options (optional).
+// Returns pointer to integer with starting value `0`. Every appearance of the argument increments it,
+// including repeated shorthand in one argument, so `-vvv` and `-v --verbose -v` both count to 3.
+// Same as for Flag, shorthand can be combined with other flags.
+func (o *Command) FlagCounter(short string, long string, opts *Options) *int {
+	var result int
+
+	a := &arg{
+		result:  &result,
+		sname:   short,
+		lname:   long,
+		size:    1,
+		opts:    opts,
+		unique:  false,
+		counter: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// VerbosityLevel creates new flag counter argument that maps number of times it was provided on CLI
+// to one of named levels. Takes short name, long name, list of level names starting with the one used
+// when argument is not provided, and pointer to options (optional).
+// Same as for FlagCounter, `-vv` and `-v --verbose` both select levels[2]. Providing the argument
+// more times than there are levels selects the last level.
+// Passing empty list of levels is a programming error and will panic.
+func (o *Command) VerbosityLevel(short string, long string, levels []string, opts *Options) *string {
+	if len(levels) == 0 {
+		panic(fmt.Sprintf("argparse: [%s] VerbosityLevel requires at least one level", long))
+	}
+
+	result := levels[0]
+
+	a := &arg{
+		result:  &result,
+		sname:   short,
+		lname:   long,
+		size:    1,
+		opts:    opts,
+		unique:  false,
+		counter: true,
+		levels:  levels,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Env creates new string argument that takes its value only from environment variable with provided name and
+// never from CLI, such as API key that should not show up in shell history. It is checked for being Required,
+// validated and gets its Default the same way as any other argument, and is listed in Usage as environment
+// variable. Takes name of environment variable and (optional) options, Options.EnvVar is ignored.
+// Returns a pointer to a string, which is empty if variable is not set or empty and there is no Default.
+func (o *Command) Env(name string, opts *Options) *string {
+	var result string
+
+	options := Options{}
+	if opts != nil {
+		options = *opts
+	}
+	options.EnvVar = name
+	a := &arg{
+		result: &result,
+		lname:  name,
+		size:   1,
+		opts:   &options,
+		unique: true,
+		env:    true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
 // String creates new string argument, which will return whatever follows the argument on CLI.
 // Takes as arguments short name (must be single character or an empty string)
 // long name and (optional) options
@@ -139,12 +531,452 @@ func (o *Command) String(short string, long string, opts *Options) *string {
 	return &result
 }
 
-// Int creates new int argument, which will attempt to parse following argument as int.
-// Takes as arguments short name (must be single character or an empty string)
-// long name and (optional) options.
-// If parsing fails parser.Parse() will return an error.
-func (o *Command) Int(short string, long string, opts *Options) *int {
-	var result int
+// Int creates new int argument, which will attempt to parse following argument as int.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) Int(short string, long string, opts *Options) *int {
+	var result int
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Uint creates new unsigned int argument, which will attempt to parse following argument as uint.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails or value is negative parser.Parse() will return an error.
+func (o *Command) Uint(short string, long string, opts *Options) *uint {
+	var result uint
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Int64 creates new int64 argument, which will attempt to parse following argument as int64
+// regardless of platform int size.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) Int64(short string, long string, opts *Options) *int64 {
+	var result int64
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Bytes creates new size argument, which will attempt to parse following argument as human-readable size,
+// such as 512, 10MB or 1.5GiB, into number of bytes. Decimal (KB, MB, GB, TB) and binary (KiB, MiB, GiB, TiB)
+// suffixes are supported, value without suffix is a number of bytes.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) Bytes(short string, long string, opts *Options) *int64 {
+	var result int64
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+		bytes:  true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Percent creates new percentage argument, which will attempt to parse following argument as percentage,
+// such as 50% or 0.5, into fraction between 0 and 1. Number with "%" suffix is divided by 100. Number without
+// it is ambiguous: it is taken as fraction when it is between 0 and 1, and as percentage when it is above 1
+// and up to 100, so that "1" means 100% and "2" means 2%. Anything outside of 0% to 100% is an error.
+// Min, Max and Default are fractions as well.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) Percent(short string, long string, opts *Options) *float64 {
+	var result float64
+
+	a := &arg{
+		result:  &result,
+		sname:   short,
+		lname:   long,
+		size:    2,
+		opts:    opts,
+		unique:  true,
+		percent: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Float creates new float argument, which will attempt to parse following argument as float64.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) Float(short string, long string, opts *Options) *float64 {
+	var result float64
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Duration creates new duration argument, which will attempt to parse following argument as time.Duration
+// (see time.ParseDuration for accepted format, such as "300ms" or "1h30m").
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) Duration(short string, long string, opts *Options) *time.Duration {
+	var result time.Duration
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Time creates new time argument, which will attempt to parse following argument as time.Time with provided
+// layout (see time.Parse for details on layouts). Empty layout defaults to time.RFC3339, so that every time
+// argument can use its own format.
+// Takes as arguments short name (must be single character or an empty string)
+// long name, layout and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) Time(short string, long string, layout string, opts *Options) *time.Time {
+	var result time.Time
+
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+		layout: layout,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// IP creates new IP address argument, which will attempt to parse following argument as IPv4 or IPv6 address.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) IP(short string, long string, opts *Options) *net.IP {
+	var result net.IP
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// MAC creates new MAC address argument, which will attempt to parse following argument as IEEE 802 MAC-48,
+// EUI-48 or EUI-64 address, such as "01:23:45:67:89:ab" or "01-23-45-67-89-ab".
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) MAC(short string, long string, opts *Options) *net.HardwareAddr {
+	var result net.HardwareAddr
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// IPNet creates new network argument, which will attempt to parse following argument as IPv4 or IPv6 network
+// in CIDR notation, such as "10.0.0.0/8". Host part of the address is dropped, so "10.1.2.3/8" is "10.0.0.0/8".
+// Default value is expected to be a string in same notation.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) IPNet(short string, long string, opts *Options) *net.IPNet {
+	var result net.IPNet
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// URL creates new URL argument, which will attempt to parse following argument as an absolute URL with host,
+// such as "https://api.example.com". Allowed schemes can be limited with Options.Schemes.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// If parsing fails parser.Parse() will return an error.
+func (o *Command) URL(short string, long string, opts *Options) *url.URL {
+	var result url.URL
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// Regexp creates new regular expression argument, which will attempt to compile provided value.
+// Takes as arguments short name (must be single character or an empty string)
+// long name and (optional) options.
+// Returns a pointer to compiled expression, which stays nil if argument was not provided.
+// If compilation fails parser.Parse() will return an error.
+func (o *Command) Regexp(short string, long string, opts *Options) **regexp.Regexp {
+	var result *regexp.Regexp
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// JSON creates new JSON argument, which will attempt to unmarshal following argument into provided target
+// with json.Unmarshal. Target must be a non-nil pointer, passing anything else is a programming error and
+// will panic when argument is created.
+// Takes as arguments short name (must be single character or an empty string)
+// long name, target and (optional) options.
+// Returns a pointer to the raw JSON string. If unmarshalling fails parser.Parse() will return an error.
+func (o *Command) JSON(short string, long string, target interface{}, opts *Options) *string {
+	var result string
+
+	a := &arg{
+		result:     &result,
+		sname:      short,
+		lname:      long,
+		size:       2,
+		opts:       opts,
+		unique:     true,
+		jsonTarget: target,
+	}
+
+	if v := reflect.ValueOf(target); v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Sprintf("argparse: [%s] JSON target must be a non-nil pointer, got [%T]", a.name(), target))
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// File creates new file argument, which is when provided will check if file exists or attempt to create it
+// depending on provided flags (same as for os.OpenFile).
+// It takes same as all other arguments short and long names, additionally it takes flags that specify
+// in which mode the file should be open (see os.OpenFile for details on that), file permissions that
+// will be applied to a file and argument options.
+// Returns a pointer to os.File which will be set to opened file on success. On error the Parser.Parse
+// will return error and the pointer might be nil.
+func (o *Command) File(short string, long string, flag int, perm os.FileMode, opts *Options) *os.File {
+	var result os.File
+
+	a := &arg{
+		result:   &result,
+		sname:    short,
+		lname:    long,
+		size:     2,
+		opts:     opts,
+		unique:   true,
+		fileFlag: flag,
+		filePerm: perm,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// FileList creates new file list argument. This is the argument that is allowed to be present multiple times on CLI.
+// Every appearance of the argument is opened same as File argument would be, with provided flags and permissions,
+// and collected into the list in order of appearance. If any of the files cannot be opened, all files opened so far
+// are closed and Parser.Parse will return error.
+// Returns a pointer to the list of os.File. If no argument provided, then the list is empty.
+func (o *Command) FileList(short string, long string, flag int, perm os.FileMode, opts *Options) *[]os.File {
+	result := make([]os.File, 0)
+
+	a := &arg{
+		result:   &result,
+		sname:    short,
+		lname:    long,
+		size:     2,
+		opts:     opts,
+		unique:   false,
+		fileFlag: flag,
+		filePerm: perm,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// List creates new list argument. This is the argument that is allowed to be present multiple times on CLI.
+// All appearances of this argument on CLI will be collected into the list of strings. If no argument
+// provided, then the list is empty. Takes same parameters as String
+// Returns a pointer the list of strings.
+func (o *Command) List(short string, long string, opts *Options) *[]string {
+	result := make([]string, 0)
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: false,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// IntList creates new integer list argument. This is the argument that is allowed to be present multiple times on CLI.
+// All appearances of this argument on CLI will be parsed as integers and collected into the list in order of
+// appearance. If no argument provided, then the list is empty. Takes same parameters as Int.
+// Returns a pointer the list of integers.
+func (o *Command) IntList(short string, long string, opts *Options) *[]int {
+	result := make([]int, 0)
+
+	a := &arg{
+		result: &result,
+		sname:  short,
+		lname:  long,
+		size:   2,
+		opts:   opts,
+		unique: false,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// ListFunc creates new list argument which elements are made by parse function, such as "host:port" pairs
+// parsed into structures. This is the argument that is allowed to be present multiple times on CLI. Every
+// value is passed to parse and whatever it returns is appended to the list in order of appearance, error
+// it returns fails parsing with the name of argument and the value. If no argument provided, then the list
+// is empty. Takes as arguments short name (must be single character or an empty string), long name,
+// parse function and (optional) options. Default must be []interface{}.
+// Returns a pointer to the list of elements, which the caller converts to their type.
+func (o *Command) ListFunc(short string, long string, parse func(string) (interface{}, error), opts *Options) *[]interface{} {
+	result := make([]interface{}, 0)
+
+	a := &arg{
+		result:   &result,
+		sname:    short,
+		lname:    long,
+		size:     2,
+		opts:     opts,
+		unique:   false,
+		listFunc: parse,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// FloatList creates new floating point list argument. This is the argument that is allowed to be present multiple
+// times on CLI. All appearances of this argument on CLI will be parsed as floats and collected into the list in order
+// of appearance. If no argument provided, then the list is empty. Takes same parameters as Float.
+// Returns a pointer the list of floats.
+func (o *Command) FloatList(short string, long string, opts *Options) *[]float64 {
+	result := make([]float64, 0)
 
 	a := &arg{
 		result: &result,
@@ -152,7 +984,7 @@ func (o *Command) Int(short string, long string, opts *Options) *int {
 		lname:  long,
 		size:   2,
 		opts:   opts,
-		unique: true,
+		unique: false,
 	}
 
 	o.addArg(a)
@@ -160,12 +992,13 @@ func (o *Command) Int(short string, long string, opts *Options) *int {
 	return &result
 }
 
-// Float creates new float argument, which will attempt to parse following argument as float64.
-// Takes as arguments short name (must be single character or an empty string)
-// long name and (optional) options.
-// If parsing fails parser.Parse() will return an error.
-func (o *Command) Float(short string, long string, opts *Options) *float64 {
-	var result float64
+// StringMap creates new map argument. This is the argument that is allowed to be present multiple times on CLI.
+// Every appearance of this argument on CLI must be in "key=value" form, it is split on the first "=" and put into
+// the map. Value of a repeated key overwrites the previous one, unless Options.UniqueKeys is set.
+// If no argument provided, then the map is empty. Takes same parameters as String.
+// Returns a pointer to the map of strings.
+func (o *Command) StringMap(short string, long string, opts *Options) *map[string]string {
+	result := make(map[string]string)
 
 	a := &arg{
 		result: &result,
@@ -173,7 +1006,7 @@ func (o *Command) Float(short string, long string, opts *Options) *float64 {
 		lname:  long,
 		size:   2,
 		opts:   opts,
-		unique: true,
+		unique: false,
 	}
 
 	o.addArg(a)
@@ -181,15 +1014,14 @@ func (o *Command) Float(short string, long string, opts *Options) *float64 {
 	return &result
 }
 
-// File creates new file argument, which is when provided will check if file exists or attempt to create it
-// depending on provided flags (same as for os.OpenFile).
-// It takes same as all other arguments short and long names, additionally it takes flags that specify
-// in which mode the file should be open (see os.OpenFile for details on that), file permissions that
-// will be applied to a file and argument options.
-// Returns a pointer to os.File which will be set to opened file on success. On error the Parser.Parse
-// will return error and the pointer might be nil.
-func (o *Command) File(short string, long string, flag int, perm os.FileMode, opts *Options) *os.File {
-	var result os.File
+// Selector creates a selector argument. Selector argument works in the same way as String argument, with
+// the difference that the string value must be from the list of options provided by the program.
+// Takes short and long names, argument options and a slice of strings which are allowed values
+// for CLI argument.
+// Returns a pointer to a string. If argument is not required (as in argparse.Options.Required),
+// and argument was not provided, then the string is empty.
+func (o *Command) Selector(short string, long string, options []string, opts *Options) *string {
+	var result string
 
 	a := &arg{
 		result:   &result,
@@ -198,8 +1030,7 @@ func (o *Command) File(short string, long string, flag int, perm os.FileMode, op
 		size:     2,
 		opts:     opts,
 		unique:   true,
-		fileFlag: flag,
-		filePerm: perm,
+		selector: &options,
 	}
 
 	o.addArg(a)
@@ -207,20 +1038,53 @@ func (o *Command) File(short string, long string, flag int, perm os.FileMode, op
 	return &result
 }
 
-// List creates new list argument. This is the argument that is allowed to be present multiple times on CLI.
-// All appearances of this argument on CLI will be collected into the list of strings. If no argument
-// provided, then the list is empty. Takes same parameters as String
-// Returns a pointer the list of strings.
-func (o *Command) List(short string, long string, opts *Options) *[]string {
+// Positional creates new positional argument. Positional argument has no short or long name on CLI,
+// instead it takes its value by position from arguments left after all named arguments were processed.
+// Positional arguments are filled left-to-right in order of creation. Arguments that follow "--" on CLI
+// are never treated as argument names and can be used as positional values even if they start with "-".
+// Takes name that is used in Usage output and error messages and (optional) options.
+// Returns a pointer to a string. If argument is not required (as in argparse.Options.Required),
+// and argument was not provided, then the string is empty.
+func (o *Command) Positional(name string, opts *Options) *string {
+	var result string
+
+	a := &arg{
+		result:     &result,
+		lname:      name,
+		size:       1,
+		opts:       opts,
+		unique:     true,
+		positional: true,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// PositionalList creates new positional argument that takes all values left once other positional arguments
+// of this Command were filled, such as source files in `cp SRC... DST`. Positional arguments created after the
+// list take their values from the end. Values that follow "--" on CLI are taken as well.
+// Takes name that is used in Usage output and error messages and (optional) options. Required means that at least
+// one value must be provided. Command can have only one positional list, creating another one is a programming
+// error and will panic.
+// Returns a pointer to a slice of strings, which is empty if no values were provided.
+func (o *Command) PositionalList(name string, opts *Options) *[]string {
 	result := make([]string, 0)
 
+	for _, v := range o.args {
+		if v.positional && v.isList() {
+			panic(fmt.Sprintf("argparse: [%s] command already has positional list [%s]", o.name, v.lname))
+		}
+	}
+
 	a := &arg{
-		result: &result,
-		sname:  short,
-		lname:  long,
-		size:   2,
-		opts:   opts,
-		unique: false,
+		result:     &result,
+		lname:      name,
+		size:       1,
+		opts:       opts,
+		unique:     false,
+		positional: true,
 	}
 
 	o.addArg(a)
@@ -228,14 +1092,45 @@ func (o *Command) List(short string, long string, opts *Options) *[]string {
 	return &result
 }
 
-// Selector creates a selector argument. Selector argument works in the same way as String argument, with
-// the difference that the string value must be from the list of options provided by the program.
+// Choice creates a selector argument that maps allowed string values to arbitrary values, such as constants
+// of an enumeration. It works in the same way as Selector, with the difference that the resulting value is the
+// one from choices map matching provided key. Allowed keys are listed in sorted order in Usage and errors.
+// Returns a pointer to an interface value. If argument is not required (as in argparse.Options.Required),
+// and argument was not provided, then the value is nil.
+func (o *Command) Choice(short string, long string, choices map[string]interface{}, opts *Options) *interface{} {
+	var result interface{}
+
+	options := make([]string, 0, len(choices))
+	for k := range choices {
+		options = append(options, k)
+	}
+	sort.Strings(options)
+
+	a := &arg{
+		result:   &result,
+		sname:    short,
+		lname:    long,
+		size:     2,
+		opts:     opts,
+		unique:   true,
+		selector: &options,
+		choices:  choices,
+	}
+
+	o.addArg(a)
+
+	return &result
+}
+
+// SelectorIndex creates a selector argument that works in the same way as Selector, with the difference
+// that the result is the position of chosen value in the list of options rather than the value itself.
+// Useful when options map to an enumeration.
 // Takes short and long names, argument options and a slice of strings which are allowed values
 // for CLI argument.
-// Returns a pointer to a string. If argument is not required (as in argparse.Options.Required),
-// and argument was not provided, then the string is empty.
-func (o *Command) Selector(short string, long string, options []string, opts *Options) *string {
-	var result string
+// Returns a pointer to an integer. If argument is not required (as in argparse.Options.Required),
+// and argument was not provided, then the integer is -1.
+func (o *Command) SelectorIndex(short string, long string, options []string, opts *Options) *int {
+	result := -1
 
 	a := &arg{
 		result:   &result,
@@ -252,12 +1147,168 @@ func (o *Command) Selector(short string, long string, options []string, opts *Op
 	return &result
 }
 
+// AddNote adds line of text to the list of arguments in Usage output, such as a header or explanation of
+// the arguments that follow. It is shown right after the argument created before it, or at the top of the
+// list if there is none. Note is not an argument, so it is never matched on CLI or checked in any way.
+func (o *Command) AddNote(text string) {
+	var after *arg
+	if len(o.args) > 0 {
+		after = o.args[len(o.args)-1]
+	}
+	o.notes = append(o.notes, note{after: after, text: text})
+}
+
+// NewMutexGroup makes arguments mutually exclusive, so that at most one of them can be provided on CLI.
+// Takes pointers returned when arguments were created on this Command or any of preceding commands.
+// The check is done once all arguments were parsed, and only if this Command was used.
+// Passing a pointer that does not belong to any argument is a programming error and will panic.
+func (o *Command) NewMutexGroup(results ...interface{}) {
+	g := &FlagGroup{
+		args: o.findArgs(results),
+		max:  1,
+	}
+
+	o.groups = append(o.groups, g)
+}
+
+// RequireOneOf makes at least one of the arguments required to be provided on CLI.
+// Takes pointers returned when arguments were created on this Command or any of preceding commands.
+// The check is done once all arguments were parsed, and only if this Command was used.
+// Passing a pointer that does not belong to any argument is a programming error and will panic.
+func (o *Command) RequireOneOf(results ...interface{}) {
+	g := &FlagGroup{
+		args: o.findArgs(results),
+		min:  1,
+	}
+
+	o.groups = append(o.groups, g)
+}
+
+// AtMostOne works same as NewMutexGroup and returns the group that was added.
+func (o *Command) AtMostOne(results ...interface{}) *FlagGroup {
+	g := &FlagGroup{
+		args: o.findArgs(results),
+		max:  1,
+	}
+
+	o.groups = append(o.groups, g)
+
+	return g
+}
+
+// ExactlyOne makes exactly one of the arguments required to be provided on CLI, so that providing
+// none or several of them is an error. Takes same parameters as NewMutexGroup and returns the group that was added.
+func (o *Command) ExactlyOne(results ...interface{}) *FlagGroup {
+	g := &FlagGroup{
+		args: o.findArgs(results),
+		min:  1,
+		max:  1,
+	}
+
+	o.groups = append(o.groups, g)
+
+	return g
+}
+
 // Happened shows whether Command was specified on CLI arguments or not. If Command did not "happen", then
 // all its descendant commands and arguments are not parsed. Returns a boolean value.
 func (o *Command) Happened() bool {
 	return o.parsed
 }
 
+// Occurrences returns how many times argument was provided on CLI, counting every short name in combined
+// shorthand flags such as "-vvv", as well as its environment variable. Negated form and Default are not counted.
+// Argument is referenced by pointer returned from its constructor and may belong to any command of this Parser.
+// Panics if pointer is not a known argument
+func (o *Command) Occurrences(result interface{}) int {
+	root := o
+	for root.parent != nil {
+		root = root.parent
+	}
+	a := root.lookupArg(result)
+	if a == nil {
+		panic(fmt.Sprintf("argparse: [%T] is not an argument of [%s] command", result, root.name))
+	}
+	return a.occurrences
+}
+
+// Name returns name of Command as it is typed on CLI. Name of Parser is the program name.
+func (o *Command) Name() string {
+	return o.name
+}
+
+// Description returns description of Command as it was provided on creation.
+func (o *Command) Description() string {
+	return o.description
+}
+
+// Commands returns sub-commands of Command in order they were created.
+func (o *Command) Commands() []*Command {
+	return append([]*Command(nil), o.commands...)
+}
+
+// WasSet shows whether argument was specified on CLI arguments or its environment variable. Argument
+// is referenced by pointer returned from its constructor and may belong to any command of this Parser.
+// Argument that only received its Default value was not set. Panics if pointer is not a known argument
+func (o *Command) WasSet(result interface{}) bool {
+	root := o
+	for root.parent != nil {
+		root = root.parent
+	}
+	a := root.lookupArg(result)
+	if a == nil {
+		panic(fmt.Sprintf("argparse: [%T] is not an argument of [%s] command", result, root.name))
+	}
+	return a.parsed
+}
+
+// ArgInfo describes an argument of a Command, as returned by Arguments. It is a copy, so changing it does not
+// change the argument
+type ArgInfo struct {
+	Short      string
+	Long       string
+	Type       string
+	Help       string
+	Required   bool
+	Positional bool
+	Env        bool
+	Hidden     bool
+	Default    interface{}
+	Selector   []string
+	Group      string
+}
+
+// Arguments returns description of every argument of this Command in order they were created, including help
+// argument of Parser. Arguments of preceding commands and sub-commands are not included. Type is a short name
+// of argument type, such as "flag", "string", "int-list" or "selector". Names are without prefixes, and
+// Positional arguments have their name in Long, as do Env arguments with the name of environment variable.
+// Selector holds allowed values of Selector, SelectorIndex and Choice arguments. Group is the section of
+// Usage output set with Options.Group.
+func (o *Command) Arguments() []ArgInfo {
+	result := make([]ArgInfo, 0, len(o.args))
+	for _, v := range o.args {
+		info := ArgInfo{
+			Short:      v.sname,
+			Long:       v.lname,
+			Type:       v.typeName(),
+			Positional: v.positional,
+			Env:        v.env,
+			Hidden:     v.hidden(),
+		}
+		if v.opts != nil {
+			info.Help = v.opts.Help
+			info.Required = v.opts.Required
+			info.Default = v.opts.Default
+			info.Group = v.opts.Group
+		}
+		if v.selector != nil {
+			info.Selector = append([]string(nil), *v.selector...)
+		}
+		result = append(result, info)
+	}
+	return result
+}
+
 // Usage returns a multiline string that is the same as a help message for this Parser or Command.
 // Since Parser is a Command as well, they work in exactly same way. Meaning that usage string
 // can be retrieved for any level of commands. It will only include information about this Command,
@@ -265,10 +1316,24 @@ func (o *Command) Happened() bool {
 //
 // Accepts an interface that can be error, string or fmt.Stringer that will be prepended to a message.
 // All other interface types will be ignored
+//
+// When Parser.HelpFunc is set, message is followed by whatever it returns for this Command instead
 func (o *Command) Usage(msg interface{}) string {
+	return o.usage(msg, true)
+}
+
+// DefaultUsage returns same help message as Usage does when Parser.HelpFunc is not set. Useful for HelpFunc
+// that only adds to the built-in help message
+func (o *Command) DefaultUsage(msg interface{}) string {
+	return o.usage(msg, false)
+}
+
+func (o *Command) usage(msg interface{}, custom bool) string {
 	var result string
-	// Stay classy
-	maxWidth := 80
+	if p := o.getParser(); p != nil {
+		p.syncHelp()
+	}
+	maxWidth := o.usageWidth()
 	// List of arguments from all preceding commands
 	arguments := make([]*arg, 0)
 	// First get line of commands until root
@@ -279,7 +1344,7 @@ func (o *Command) Usage(msg interface{}) string {
 		case subCommandError:
 			result = fmt.Sprintf("%s\n", msg.(error).Error())
 			if msg.(subCommandError).cmd != nil {
-				result += msg.(subCommandError).cmd.Usage(nil)
+				result += msg.(subCommandError).cmd.usage(nil, custom)
 			}
 			return result
 		case error:
@@ -290,14 +1355,15 @@ func (o *Command) Usage(msg interface{}) string {
 			result = fmt.Sprintf("%s\n", msg.(fmt.Stringer).String())
 		}
 	}
+	if p := o.getParser(); custom && p != nil && p.HelpFunc != nil {
+		return result + p.HelpFunc(o)
+	}
 	for current != nil {
 		chain = append(chain, current.name)
-		// Also add arguments
-		if current.args != nil {
-			arguments = append(arguments, current.args...)
-		}
 		current = current.parent
 	}
+	// Also add arguments
+	arguments = append(arguments, o.availableArgs()...)
 	// Reverse the slice
 	last := len(chain) - 1
 	for i := 0; i < len(chain)/2; i++ {
@@ -323,12 +1389,28 @@ func (o *Command) Usage(msg interface{}) string {
 	for _, v := range chain {
 		result = addToLastLine(result, v, maxWidth, leftPadding, true)
 	}
-	// Add arguments from this and all preceding commands
+	// Positional and environment arguments are listed separately from named ones
+	positionals := make([]*arg, 0)
+	named := make([]*arg, 0)
+	env := make([]*arg, 0)
 	for _, v := range arguments {
 		// Skip arguments that are hidden
-		if v.opts.Help == DisableDescription {
+		if v.hidden() {
 			continue
 		}
+		if v.positional {
+			positionals = append(positionals, v)
+		} else if v.env {
+			env = append(env, v)
+		} else {
+			named = append(named, v)
+		}
+	}
+	// Add arguments from this and all preceding commands
+	for _, v := range named {
+		result = addToLastLine(result, v.usage(), maxWidth, leftPadding, true)
+	}
+	for _, v := range positionals {
 		result = addToLastLine(result, v.usage(), maxWidth, leftPadding, true)
 	}
 
@@ -346,8 +1428,8 @@ func (o *Command) Usage(msg interface{}) string {
 			if com.description == DisableDescription {
 				continue
 			}
-			if len("  "+com.name+"  ") > cmdPadding {
-				cmdPadding = len("  " + com.name + "  ")
+			if len("  "+com.usageName()+"  ") > cmdPadding {
+				cmdPadding = len("  " + com.usageName() + "  ")
 			}
 		}
 		// Now add commands with known padding
@@ -355,49 +1437,257 @@ func (o *Command) Usage(msg interface{}) string {
 			if com.description == DisableDescription {
 				continue
 			}
-			cmd := "  " + com.name
+			cmd := "  " + com.usageName()
 			cmd = cmd + strings.Repeat(" ", cmdPadding-len(cmd)-1)
-			cmd = addToLastLine(cmd, com.description, maxWidth, cmdPadding, true)
+			cmd = addToLastLine(cmd, com.summary(), maxWidth, cmdPadding, true)
 			cmdContent = cmdContent + cmd + "\n"
 		}
 		result = result + cmdContent + "\n"
 	}
 
+	// Add list of positional arguments to the result
+	if len(positionals) > 0 {
+		posContent := "Positional arguments:\n\n"
+		// Get biggest padding
+		var posPadding int
+		for _, argument := range positionals {
+			if len("  "+argument.lname+"  ") > posPadding {
+				posPadding = len("  " + argument.lname + "  ")
+			}
+		}
+		// Now add positional args with known padding
+		for _, argument := range positionals {
+			arg := "  " + argument.lname
+			arg = arg + strings.Repeat(" ", posPadding-len(arg)-1)
+			if argument.opts != nil && argument.opts.Help != "" {
+				arg = addToLastLine(arg, argument.getHelpMessage(), maxWidth, posPadding, true)
+			}
+			arg = arg + argument.getExamples(maxWidth, posPadding)
+			posContent = posContent + arg + "\n"
+		}
+		result = result + posContent + "\n"
+	}
+
 	// Add list of arguments to the result
-	if len(arguments) > 0 {
-		argContent := "Arguments:\n\n"
+	if len(named) > 0 {
+		short, long := o.prefixes()
 		// Get biggest padding
 		var argPadding int
 		// Find biggest padding
-		for _, argument := range arguments {
-			if argument.opts.Help == DisableDescription {
-				continue
+		for _, argument := range named {
+			if len(short)+len(long)+len(argument.lname)+6 > argPadding {
+				argPadding = len(short) + len(long) + len(argument.lname) + 6
+			}
+		}
+		// Cluster args by their groups in order of first appearance, ungrouped ones go to default section
+		sections := make([]string, 0)
+		grouped := make(map[string][]*arg)
+		for _, argument := range named {
+			var section string
+			if argument.opts != nil {
+				section = argument.opts.Group
 			}
-			if len(argument.lname)+9 > argPadding {
-				argPadding = len(argument.lname) + 9
+			if _, ok := grouped[section]; !ok {
+				sections = append(sections, section)
 			}
+			grouped[section] = append(grouped[section], argument)
 		}
+		notes := o.notesAfter(named)
 		// Now add args with padding
-		for _, argument := range arguments {
-			if argument.opts.Help == DisableDescription {
-				continue
+		for i, section := range sections {
+			argContent := "Arguments:\n\n"
+			if section != "" {
+				argContent = section + ":\n\n"
 			}
-			arg := "  "
-			if argument.sname != "" {
-				arg = arg + "-" + argument.sname + "  "
-			} else {
-				arg = arg + "    "
+			if i == 0 {
+				for _, text := range notes[nil] {
+					argContent = argContent + addToLastLine(" ", text, maxWidth, 2, true) + "\n"
+				}
 			}
-			arg = arg + "--" + argument.lname
-			arg = arg + strings.Repeat(" ", argPadding-len(arg))
-			if argument.opts != nil && argument.opts.Help != "" {
-				arg = addToLastLine(arg, argument.getHelpMessage(), maxWidth, argPadding, true)
+			for _, argument := range grouped[section] {
+				arg := "  "
+				if argument.sname != "" {
+					arg = arg + short + argument.sname + "  "
+				} else {
+					arg = arg + strings.Repeat(" ", len(short)+3)
+				}
+				arg = arg + long + argument.lname
+				arg = arg + strings.Repeat(" ", argPadding-len(arg))
+				if argument.opts != nil && argument.getHelpMessage() != "" {
+					arg = addToLastLine(arg, argument.getHelpMessage(), maxWidth, argPadding, true)
+				}
+				arg = arg + argument.getExamples(maxWidth, argPadding)
+				argContent = argContent + arg + "\n"
+				for _, text := range notes[argument] {
+					argContent = argContent + addToLastLine(" ", text, maxWidth, 2, true) + "\n"
+				}
+			}
+			result = result + argContent + "\n"
+		}
+	}
+
+	// Add list of environment variables to the result
+	if len(env) > 0 {
+		envContent := "Environment variables:\n\n"
+		var envPadding int
+		for _, argument := range env {
+			if len("  "+argument.lname+"  ") > envPadding {
+				envPadding = len("  " + argument.lname + "  ")
+			}
+		}
+		for _, argument := range env {
+			arg := "  " + argument.lname
+			arg = arg + strings.Repeat(" ", envPadding-len(arg)-1)
+			if argument.getHelpMessage() != "" {
+				arg = addToLastLine(arg, argument.getHelpMessage(), maxWidth, envPadding, true)
+			}
+			arg = arg + argument.getExamples(maxWidth, envPadding)
+			envContent = envContent + arg + "\n"
+		}
+		result = result + envContent + "\n"
+	}
+
+	// Add constraints of argument groups to the result
+	if groups := o.availableGroups(); len(groups) > 0 {
+		groupContent := "Constraints:\n\n"
+		for _, g := range groups {
+			groupContent = groupContent + addToLastLine(" ", g.hint(), maxWidth, 2, true) + "\n"
+		}
+		result = result + groupContent + "\n"
+	}
+
+	// Add epilog of Parser to the result
+	if o.parser != nil && o.parser.Epilog != "" {
+		for _, v := range strings.Split(o.parser.Epilog, "\n") {
+			if v != "" {
+				result = result + addToLastLine(" ", v, maxWidth, 1, true)
+			}
+			result = result + "\n"
+		}
+		result = result + "\n"
+	}
+
+	return result
+}
+
+// Reset brings Parser back to the state it had before parsing, so that it can parse another command line.
+// All commands and arguments are marked as not provided, results of arguments are set to zero values, lists and
+// maps become empty, and Remaining and Unknown return nothing. Definitions and settings of Parser are kept as is.
+// Files opened by File and FileList arguments are not closed, and targets of JSON arguments keep their values.
+func (o *Parser) Reset() {
+	o.walkCommands(nil, func(path []string, cmd *Command) {
+		cmd.parsed = false
+		for _, v := range cmd.args {
+			v.reset()
+		}
+	})
+	o.remaining = nil
+	o.unknown = nil
+	o.errors = nil
+	o.onParse = nil
+	o.succeeded = false
+}
+
+// SetVersion adds "-V|--version" argument that prints version and exits the program with Parser.ExitFunc, the
+// same way help does. It is handled before any other argument is checked, so that version is printed even if
+// required arguments are missing. Version is written to stdout, or to writer set with SetOutput. Empty version
+// removes the argument.
+func (o *Parser) SetVersion(version string) {
+	o.version = version
+	o.syncHelp()
+}
+
+// Validate checks definitions of all arguments of the program and its commands, without parsing anything, and
+// returns the first problem found. These are arguments that were ignored because their name is already used or
+// is not valid, required arguments that have Default, which is never used, Default that cannot be used as the
+// value of argument, such as value that is not one of Selector options, and options set for types they do not
+// apply to, such as Base for non-integer arguments. Call it from a test or at startup to catch mistakes that
+// otherwise show up only once a particular command line is parsed. Min greater than Max panics right away.
+func (o *Parser) Validate() error {
+	var result error
+	o.walkCommands(nil, func(path []string, cmd *Command) {
+		if result != nil {
+			return
+		}
+		if len(cmd.ignored) > 0 {
+			result = cmd.ignored[0]
+			return
+		}
+		for _, v := range cmd.args {
+			if err := v.validate(); err != nil {
+				result = err
+				return
 			}
-			argContent = argContent + arg + "\n"
 		}
-		result = result + argContent + "\n"
+	})
+	return result
+}
+
+// SetPrefixes sets prefixes that short and long names of arguments start with on CLI, which are "-" and "--"
+// by default. For example "/" and "/" accept Windows style "/v" and "/verbose". When both prefixes are the same,
+// argument that is a long name of some argument is never taken for combined short names. Usage, error messages,
+// completion and man page show names with these prefixes. Empty prefix is a programming error and will panic.
+func (o *Parser) SetPrefixes(short string, long string) {
+	if short == "" || long == "" {
+		panic("argparse: prefixes of argument names cannot be empty")
+	}
+	o.shortPrefix = short
+	o.longPrefix = long
+}
+
+// SetOutput sets the writer that usage printed for "-h|--help" is written to instead of os.Stdout. Usage and
+// error printed because of PrintUsageOnError go there as well instead of os.Stderr. Setting nil restores
+// default behavior.
+func (o *Parser) SetOutput(w io.Writer) {
+	o.output = w
+}
+
+// SetWidth sets the width that Usage output is wrapped to. By default Usage is wrapped to the
+// width of terminal as reported by COLUMNS environment variable, or to 80 characters when
+// output is not a terminal. Setting width to 0 restores default behavior.
+func (o *Parser) SetWidth(width int) {
+	o.width = width
+}
+
+// Remaining returns arguments that followed "--" terminator on CLI and were not taken by positional
+// arguments. These are never matched against argument names and are returned exactly as provided,
+// which is useful for passing them to another program. Returns empty slice if there were none.
+func (o *Parser) Remaining() []string {
+	if o.remaining == nil {
+		return make([]string, 0)
+	}
+	return o.remaining
+}
+
+// Unknown returns arguments that looked like argument names, but did not match any argument, in order of
+// appearance. These are only collected when Parser.PassThroughUnknown is set, otherwise they are an error.
+// Returns empty slice if there were none.
+func (o *Parser) Unknown() []string {
+	if o.unknown == nil {
+		return make([]string, 0)
 	}
+	return o.unknown
+}
+
+// Parsed shows whether the last call to Parse succeeded, so that results of arguments are safe to use.
+func (o *Parser) Parsed() bool {
+	return o.succeeded
+}
+
+// InvokedCommand returns the deepest command that was invoked on CLI, such as "add" of "progname remote add".
+// Returns embedded Command of Parser itself if no sub-command was invoked.
+func (o *Parser) InvokedCommand() *Command {
+	return o.invoked()
+}
 
+// CommandPath returns names of invoked commands in order, such as ["remote" "add"] for "progname remote add".
+// Commands are named as defined, even if they were invoked by an alias. Returns empty slice if no sub-command
+// was invoked.
+func (o *Parser) CommandPath() []string {
+	result := make([]string, 0)
+	for current := o.invoked(); current.parent != nil; current = current.parent {
+		result = append([]string{current.name}, result...)
+	}
 	return result
 }
 
@@ -409,18 +1699,169 @@ func (o *Command) Usage(msg interface{}) string {
 // In case no error returned all arguments should be safe to use. Safety of using arguments
 // before Parse operation is complete is not guaranteed.
 func (o *Parser) Parse(args []string) error {
+	err := o.parseArgs(args)
+	o.succeeded = err == nil
+	if err != nil && err != ErrHelpRequested && err != ErrVersionRequested && o.PrintUsageOnError && len(args) > 0 {
+		var w io.Writer = os.Stderr
+		if o.output != nil {
+			w = o.output
+		}
+		fmt.Fprint(w, o.commandFor(args[1:]).Usage(err))
+	}
+	return err
+}
+
+// ParseArgs works as Parse for arguments that do not start with program name, such as those read from
+// a config file or a REPL rather than os.Args. Name of the Parser is used as program name in their place.
+func (o *Parser) ParseArgs(args []string) error {
+	return o.Parse(append([]string{o.name}, args...))
+}
+
+// Report describes arguments that ParseLoose left for someone else to handle
+type Report struct {
+	// Unknown are arguments that look like names but match no argument, such as "--plugin-opt=x"
+	Unknown []UnknownArg
+	// Extra are other arguments that were not taken by any argument, such as values of unknown arguments.
+	// Only Arg and Position are set for them
+	Extra []UnknownArg
+}
+
+// UnknownArg is an argument left over by ParseLoose
+type UnknownArg struct {
+	// Arg is the argument as provided on command line, without the value attached with "="
+	Arg string
+	// Value is the value attached with "=", as in "--name=value"
+	Value string
+	// HasValue tells if value was attached, even if empty as in "--name="
+	HasValue bool
+	// Position is an index in the list passed to ParseLoose, where program name is at 0
+	Position int
+}
+
+// ParseLoose works as Parse, except that arguments which match nothing are not an error. They are
+// returned in Report instead, each with its position on command line, so that another parser can handle
+// them later. Error is returned only for problems with known arguments, such as missing required argument
+// or bad value, in which case Report still holds what was found. Arguments following "--" are not reported,
+// those not taken by positional arguments are in Remaining as usual.
+func (o *Parser) ParseLoose(args []string) (*Report, error) {
+	report := &Report{Unknown: make([]UnknownArg, 0), Extra: make([]UnknownArg, 0)}
+	o.report = report
+	defer func() {
+		o.report = nil
+	}()
+	err := o.Parse(args)
+	return report, err
+}
+
+// runOnParse calls Options.OnParse of arguments that got their values, in order they appear on command line.
+// Arguments provided more than once get a call for each value
+func (o *Parser) runOnParse() error {
+	sort.SliceStable(o.onParse, func(i, j int) bool {
+		return o.onParse[i].position < o.onParse[j].position
+	})
+	for _, v := range o.onParse {
+		err := v.arg.opts.OnParse(v.value)
+		if err != nil {
+			if err := v.arg.fail(err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseArgs does the actual parsing for Parse
+func (o *Parser) parseArgs(args []string) error {
 	subargs := make([]string, len(args))
 	copy(subargs, args)
 
+	// Response files are expanded before anything else, as if their contents were typed on command line
+	if o.responsePrefix != 0 && len(subargs) > 0 {
+		expanded, err := o.expandResponseFiles(subargs[1:], 0)
+		if err != nil {
+			return err
+		}
+		subargs = append(subargs[:1], expanded...)
+	}
+
+	// Everything after "--" terminator is never treated as argument names, unless it is the value of
+	// argument with Options.ConsumeRaw
+	rest := make([]string, 0)
+	if len(subargs) > 0 {
+		cmd := o.commandFor(subargs[1:])
+		for i := 1; i < len(subargs); i++ {
+			if subargs[i] == "--" && !cmd.rawValue(subargs, i) {
+				rest = append(rest, subargs[i+1:]...)
+				subargs = subargs[:i]
+				break
+			}
+		}
+	}
+
+	o.syncHelp()
+	o.errors = nil
+	o.onParse = nil
+	o.values = make(map[int]*arg)
+	o.size = len(subargs)
 	result := o.parse(&subargs)
+	if result == nil {
+		result = o.parsePositionals(&subargs, &rest)
+	}
+	if result == nil {
+		result = o.runOnParse()
+	}
+	if result == nil {
+		result = o.checkConstraints()
+	}
+	// Whatever follows "--" and was not taken by positional arguments is kept as is
+	o.remaining = rest
+	o.unknown = make([]string, 0)
 	unparsed := make([]string, 0)
-	for _, v := range subargs {
-		if v != "" {
-			unparsed = append(unparsed, v)
+	var stray error
+	for i, v := range subargs {
+		if v == "" {
+			continue
+		}
+		if o.report != nil {
+			unknown := UnknownArg{Arg: v, Position: o.linePosition(subargs, i)}
+			if o.isName(v) {
+				unknown.Arg, unknown.Value, unknown.HasValue = o.splitEquals(v)
+				o.report.Unknown = append(o.report.Unknown, unknown)
+			} else {
+				o.report.Extra = append(o.report.Extra, unknown)
+			}
+			continue
+		}
+		if o.OnUnknown != nil && o.isName(v) {
+			if result == nil {
+				result = o.OnUnknown(v)
+			}
+			continue
+		}
+		if o.PassThroughUnknown && o.isName(v) {
+			o.unknown = append(o.unknown, v)
+			continue
+		}
+		// In strict mode value right after the value of an argument is reported as unexpected for that argument
+		if a, ok := o.values[o.linePosition(subargs, i)-1]; ok && stray == nil && !o.isName(v) {
+			stray = o.newError(KindBadValue, "[%s] takes one value, but [%s] follows it", a.name(), v)
+		}
+		unparsed = append(unparsed, v)
+	}
+	if result == nil && stray != nil {
+		result = o.collect(stray)
+	} else if result == nil && len(unparsed) > 0 {
+		result = o.newError(KindUnknownArgument, "too many arguments")
+		for _, v := range unparsed {
+			if suggestion := o.suggest(v); suggestion != "" {
+				result = o.newError(KindUnknownArgument, "unknown argument [%s], did you mean [%s]?", v, suggestion)
+				break
+			}
 		}
+		result = o.collect(result)
 	}
-	if result == nil && len(unparsed) > 0 {
-		return errors.New("too many arguments")
+	if result == nil && len(o.errors) > 0 {
+		return parseErrors(o.errors)
 	}
 
 	return result
@@ -1,11 +1,19 @@
 package argparse
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestFlagSimple1(t *testing.T) {
@@ -129,6 +137,157 @@ func TestFlagMultiShorthand1(t *testing.T) {
 	}
 }
 
+func TestFlagMultiShorthandValue1(t *testing.T) {
+	// Value taking argument is defined both before and after the flags it is combined with
+	for _, fileFirst := range []bool{true, false} {
+		p := NewParser("", "description")
+		var file *string
+		if fileFirst {
+			file = p.String("f", "file", nil)
+		}
+		x := p.Flag("x", "extract", nil)
+		v := p.FlagCounter("v", "verbose", nil)
+		if !fileFirst {
+			file = p.String("f", "file", nil)
+		}
+		name := p.Positional("name", nil)
+
+		err := p.Parse([]string{"progname", "-xvvf", "archive.tar", "out"})
+		if err != nil {
+			t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+			return
+		}
+
+		if !*x || *v != 2 || *file != "archive.tar" || *name != "out" {
+			t.Errorf("Test %s failed. Got: x [%t], v [%d], file [%s], name [%s]", t.Name(), *x, *v, *file, *name)
+		}
+	}
+}
+
+func TestFlagMultiShorthandValue2(t *testing.T) {
+	p := NewParser("", "description")
+	_ = p.Flag("x", "extract", nil)
+	_ = p.Flag("v", "verbose", nil)
+	_ = p.String("f", "file", nil)
+
+	err := p.Parse([]string{"progname", "-xfv", "archive.tar"})
+	errStr := "[-f|--file] takes a value and must be the last of combined flags [-xfv]"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	p = NewParser("", "description")
+	_ = p.Flag("x", "extract", nil)
+	file := p.String("f", "file", nil)
+
+	err = p.Parse([]string{"progname", "-xf"})
+	errStr = "not enough arguments for -f|--file"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+	if *file != "" {
+		t.Errorf("Test %s failed. Want: [], got: [%s]", t.Name(), *file)
+	}
+}
+
+func TestFlagCounterSimple1(t *testing.T) {
+	testArgs := []string{"progname", "-vfvv", "--verbose", "--quiet"}
+
+	p := NewParser("", "description")
+	v := p.FlagCounter("v", "verbose", nil)
+	f := p.Flag("f", "force", nil)
+	q := p.FlagCounter("q", "quiet", nil)
+	d := p.FlagCounter("d", "debug", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *v != 4 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 4, *v)
+	}
+
+	if *f != true {
+		t.Errorf("Test %s failed with force being false", t.Name())
+	}
+
+	if *q != 1 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 1, *q)
+	}
+
+	if *d != 0 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 0, *d)
+	}
+}
+
+func TestShortAttachedValue1(t *testing.T) {
+	testArgs := []string{"progname", "-ofile.txt", "-abc", "-n5", "-e=a=b"}
+
+	p := NewParser("", "description")
+	o := p.String("o", "output", nil)
+	a := p.Flag("a", "aa", nil)
+	b := p.Flag("b", "bb", nil)
+	c := p.Flag("c", "cc", nil)
+	f := p.Flag("f", "ff", nil)
+	n := p.Int("n", "number", nil)
+	e := p.String("e", "expr", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *o != "file.txt" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "file.txt", *o)
+	}
+
+	if *a != true || *b != true || *c != true {
+		t.Errorf("Test %s failed. Combined flags were not all set", t.Name())
+	}
+
+	if *f != false {
+		t.Errorf("Test %s failed with ff being true", t.Name())
+	}
+
+	if *n != 5 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 5, *n)
+	}
+
+	if *e != "a=b" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "a=b", *e)
+	}
+}
+
+func TestVerbosityLevel1(t *testing.T) {
+	levels := []string{"warn", "info", "debug", "trace"}
+
+	p := NewParser("", "description")
+	v := p.VerbosityLevel("v", "verbose", levels, nil)
+	q := p.VerbosityLevel("q", "quiet", levels, nil)
+	d := p.VerbosityLevel("d", "debug", levels, nil)
+
+	err := p.Parse([]string{"progname", "-vv", "-qqqqq"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *v != "debug" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "debug", *v)
+	}
+
+	if *q != "trace" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "trace", *q)
+	}
+
+	if *d != "warn" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "warn", *d)
+	}
+}
+
 func TestFailDuplicate(t *testing.T) {
 	testArgs := []string{"progname", "--flag-arg1", "-f"}
 
@@ -315,7 +474,7 @@ func TestIntFailSimple1(t *testing.T) {
 	i1 := p.Int("f", "flag-arg1", nil)
 
 	err := p.Parse(testArgs)
-	errStr := "[-f|--flag-arg1] bad interger value [string]"
+	errStr := "[-f|--flag-arg1] must be an integer, got \"string\""
 	if err == nil || err.Error() != errStr {
 		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 		return
@@ -332,63 +491,167 @@ func TestIntFailSimple1(t *testing.T) {
 	}
 }
 
-func TestFileSimple1(t *testing.T) {
-	// Test file location
-	fpath := "./test.tmp"
-	// Create test file
-	f, err := os.Create(fpath)
+func TestUintInt64Simple1(t *testing.T) {
+	testArgs := []string{"progname", "--count", "42", "--id", "9007199254740993"}
+
+	p := NewParser("", "description")
+	u := p.Uint("c", "count", nil)
+	i := p.Int64("i", "id", nil)
+	d := p.Int64("", "other", &Options{Default: int64(-7)})
+
+	err := p.Parse(testArgs)
 	if err != nil {
-		t.Error(err)
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 		return
 	}
-	f.Close()
-	defer os.Remove(fpath)
 
-	testArgs := []string{"progname", "-f", fpath}
+	if *u != 42 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 42, *u)
+	}
 
-	p := NewParser("", "")
+	if *i != 9007199254740993 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), int64(9007199254740993), *i)
+	}
 
-	file1 := p.File("f", "file", os.O_RDWR, 0666, &Options{Default: "./non-existent-file.tmp"})
+	if *d != -7 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), -7, *d)
+	}
+}
 
-	err = p.Parse(testArgs)
+func TestUintNegativeFail1(t *testing.T) {
+	testArgs := []string{"progname", "--count", "-1"}
+
+	p := NewParser("", "description")
+	_ = p.Uint("c", "count", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-c|--count] must be a non-negative integer"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestInt64RangeFail1(t *testing.T) {
+	max := 1e12
+	testArgs := []string{"progname", "--id", "1000000000001"}
+
+	p := NewParser("", "description")
+	_ = p.Int64("i", "id", &Options{Max: &max})
+
+	err := p.Parse(testArgs)
+	errStr := "[-i|--id] must be <= 1e+12"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestBytesSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--max-size", "1.5GB", "-b", "4KiB", "--bare", "512", "--lower", "2mb"}
+
+	p := NewParser("progname", "description")
+	b1 := p.Bytes("m", "max-size", nil)
+	b2 := p.Bytes("b", "buffer", nil)
+	b3 := p.Bytes("", "bare", nil)
+	b4 := p.Bytes("", "lower", nil)
+
+	err := p.Parse(testArgs)
 	if err != nil {
 		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 		return
 	}
-	defer file1.Close()
 
-	if file1 == nil {
-		t.Errorf("Test %s failed with file1 being nil pointer", t.Name())
-		return
+	if *b1 != 1500000000 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 1500000000, *b1)
+	}
+	if *b2 != 4096 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 4096, *b2)
+	}
+	if *b3 != 512 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 512, *b3)
+	}
+	if *b4 != 2000000 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 2000000, *b4)
 	}
 
-	testString := "Test"
-	recSlice := make([]byte, 4)
-	_, err = file1.WriteString(testString)
-	if err != nil {
-		t.Errorf("Test %s write operation failed with error: %s", t.Name(), err.Error())
-		return
+	want := "usage: progname [-h|--help] [-m|--max-size <size>]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
 	}
-	file1.Seek(0, 0)
-	n, err := file1.Read(recSlice)
+}
+
+func TestBytesFail1(t *testing.T) {
+	testArgsList := map[string][]string{
+		"[-m|--max-size] has unknown size suffix \"XB\", allowed are B, KB, MB, GB, TB, KiB, MiB, GiB and TiB": {"progname", "-m", "10XB"},
+		"[-m|--max-size] must be a size such as 512, 10MB or 1.5GiB, got \"MB\"":                               {"progname", "-m", "MB"},
+		"[-m|--max-size] must be a size such as 512, 10MB or 1.5GiB, got \"-1KB\"":                             {"progname", "-m=-1KB"},
+		"[-m|--max-size] size \"10000000TB\" is too large":                                                     {"progname", "-m", "10000000TB"},
+	}
+
+	for errStr, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		_ = p.Bytes("m", "max-size", nil)
+
+		err := p.Parse(testArgs)
+		if err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		}
+	}
+}
+
+func TestPercentSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--opacity", "50%", "-f", "0.25", "--whole", "1", "--bare", "40"}
+
+	p := NewParser("progname", "description")
+	p1 := p.Percent("", "opacity", nil)
+	p2 := p.Percent("f", "fraction", nil)
+	p3 := p.Percent("", "whole", nil)
+	p4 := p.Percent("", "bare", nil)
+
+	err := p.Parse(testArgs)
 	if err != nil {
-		t.Errorf("Test %s read operation failed with error: %s", t.Name(), err.Error())
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 		return
 	}
-	if n != 4 || string(recSlice) != testString {
-		t.Errorf("Test %s failed on read operation", t.Name())
-		return
+
+	for i, want := range map[*float64]float64{p1: 0.5, p2: 0.25, p3: 1, p4: 0.4} {
+		if *i != want {
+			t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *i)
+		}
+	}
+
+	want := "usage: progname [-h|--help] [--opacity <percent>]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
 	}
 }
 
-func TestListSimple1(t *testing.T) {
-	testArgs := []string{"progname", "--flag-arg1", "test1", "--flag-arg1", "test2"}
-	list1Expect := []string{"test1", "test2"}
-	list2Expect := make([]string, 0)
+func TestPercentFail1(t *testing.T) {
+	testArgsList := map[string][]string{
+		"[--opacity] must be a percentage such as 50% or 0.5, got \"half\"":  {"progname", "--opacity", "half"},
+		"[--opacity] must be a percentage between 0% and 100%, got \"150%\"": {"progname", "--opacity", "150%"},
+		"[--opacity] must be a percentage between 0% and 100%, got \"101\"":  {"progname", "--opacity", "101"},
+		"[--opacity] must be a percentage between 0% and 100%, got \"-0.5\"": {"progname", "--opacity=-0.5"},
+		"[--opacity] must be a percentage such as 50% or 0.5, got \"NaN\"":   {"progname", "--opacity", "NaN"},
+	}
+
+	for errStr, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		_ = p.Percent("", "opacity", nil)
+
+		err := p.Parse(testArgs)
+		if err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		}
+	}
+}
+
+func TestIPSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--bind", "10.0.0.1", "--bind6", "fe80::1"}
 
 	p := NewParser("", "description")
-	l1 := p.List("f", "flag-arg1", nil)
-	l2 := p.List("", "flag-arg2", nil)
+	ip4 := p.IP("b", "bind", nil)
+	ip6 := p.IP("", "bind6", nil)
+	def := p.IP("", "gateway", &Options{Default: net.IPv4(10, 0, 0, 254)})
 
 	err := p.Parse(testArgs)
 	if err != nil {
@@ -396,34 +659,40 @@ func TestListSimple1(t *testing.T) {
 		return
 	}
 
-	if l1 == nil {
-		t.Errorf("Test %s failed with l1 being nil pointer", t.Name())
-		return
+	if !ip4.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "10.0.0.1", *ip4)
 	}
 
-	if l2 == nil {
-		t.Errorf("Test %s failed with l2 being nil pointer", t.Name())
-		return
+	if !ip6.Equal(net.ParseIP("fe80::1")) {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "fe80::1", *ip6)
 	}
 
-	if !reflect.DeepEqual(*l1, list1Expect) {
-		t.Errorf("Test %s failed. Want: %s, got: %s", t.Name(), list1Expect, *l1)
-		return
+	if !def.Equal(net.IPv4(10, 0, 0, 254)) {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "10.0.0.254", *def)
 	}
+}
 
-	if !reflect.DeepEqual(*l2, list2Expect) {
-		t.Errorf("Test %s failed. Want: %s, got: %s", t.Name(), list2Expect, *l2)
-		return
+func TestIPFail1(t *testing.T) {
+	testArgs := []string{"progname", "--bind", "10.0.0.256"}
+
+	p := NewParser("", "description")
+	_ = p.IP("b", "bind", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-b|--bind] is not a valid IP address"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 	}
 }
 
-func TestSelectorSimple1(t *testing.T) {
-	flag1Expect := "test2"
-	allowedValues := []string{"test1", flag1Expect}
-	testArgs := []string{"progname", "--flag-arg1", flag1Expect}
+func TestMACSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--mac", "01:23:45:67:89:ab", "--eui", "02-00-5e-10-00-00-00-01"}
 
-	p := NewParser("", "")
-	s1 := p.Selector("f", "flag-arg1", allowedValues, nil)
+	p := NewParser("", "description")
+	mac := p.MAC("m", "mac", nil)
+	eui := p.MAC("", "eui", nil)
+	def, _ := net.ParseMAC("00:00:5e:00:53:01")
+	gateway := p.MAC("", "gateway", &Options{Default: def})
 
 	err := p.Parse(testArgs)
 	if err != nil {
@@ -431,751 +700,4903 @@ func TestSelectorSimple1(t *testing.T) {
 		return
 	}
 
-	if s1 == nil {
-		t.Errorf("Test %s failed with s1 being nil pointer", t.Name())
-		return
+	if mac.String() != "01:23:45:67:89:ab" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "01:23:45:67:89:ab", mac)
 	}
 
-	if *s1 != flag1Expect {
-		t.Errorf("Test %s failed. Want: %s, got: %s", t.Name(), flag1Expect, *s1)
-		return
+	if eui.String() != "02:00:5e:10:00:00:00:01" || len(*eui) != 8 {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "02:00:5e:10:00:00:00:01", eui)
+	}
+
+	if gateway.String() != "00:00:5e:00:53:01" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "00:00:5e:00:53:01", gateway)
+	}
+
+	if usage := p.Usage(nil); !strings.Contains(usage, "[-m|--mac <mac>]") {
+		t.Errorf("Test %s failed. Usage does not describe MAC value:\n%s", t.Name(), usage)
 	}
 }
 
-func TestSelectorFailSimple1(t *testing.T) {
-	allowedValues := []string{"test1", "test2"}
-	testArgs := []string{"progname", "--flag-arg1", "test3"}
+func TestMACFail1(t *testing.T) {
+	testArgs := []string{"progname", "--mac", "01:23:45:67:89"}
 
-	p := NewParser("", "")
-	_ = p.Selector("f", "flag-arg1", allowedValues, nil)
+	p := NewParser("", "description")
+	_ = p.MAC("m", "mac", nil)
 
 	err := p.Parse(testArgs)
-	if err == nil {
-		t.Errorf("Test %s failed. Expected error did not happen", t.Name())
-		return
+	errStr := "[-m|--mac] is not a valid MAC address, got \"01:23:45:67:89\""
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 	}
 }
 
-func TestCommandSimple1(t *testing.T) {
-	val := 5150
-	testArgsList := [][]string{
-		{"progname", "cmd1", "--flag1", "--string-flag1", "test", "--int-flag1", strconv.Itoa(val)},
-		{"progname", "cmd2"},
+func TestIPNetSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--subnet", "10.1.2.3/8", "--subnet6", "2001:db8::/32"}
+
+	p := NewParser("", "description")
+	subnet := p.IPNet("s", "subnet", nil)
+	subnet6 := p.IPNet("", "subnet6", nil)
+	def := p.IPNet("", "private", &Options{Default: "192.168.0.0/16"})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
 	}
 
-	for _, testArgs := range testArgsList {
-		p := NewParser("progname", "description")
+	if subnet.String() != "10.0.0.0/8" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "10.0.0.0/8", subnet)
+	}
 
-		cmd1 := p.NewCommand("cmd1", "cmd1 description")
-		flag1 := cmd1.Flag("f", "flag1", nil)
-		string1 := cmd1.String("s", "string-flag1", nil)
-		int1 := cmd1.Int("i", "int-flag1", nil)
+	if subnet6.String() != "2001:db8::/32" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "2001:db8::/32", subnet6)
+	}
 
-		cmd2 := p.NewCommand("cmd2", "cmd2 description")
+	if def.String() != "192.168.0.0/16" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "192.168.0.0/16", def)
+	}
 
-		p.Parse(testArgs)
+	if usage := p.Usage(nil); !strings.Contains(usage, "[-s|--subnet <cidr>]") {
+		t.Errorf("Test %s failed. Usage does not describe IPNet value:\n%s", t.Name(), usage)
+	}
+}
 
-		if cmd1.Happened() {
-			if *flag1 != true {
-				t.Errorf("Test %s failed with %s: flag1: wanted [true], got [false]", t.Name(), testArgs[1])
-				return
-			}
-			if *string1 != "test" {
-				t.Errorf("Test %s failed with %s: string1: wanted [test], got [%s]", t.Name(), testArgs[1], *string1)
-				return
-			}
-			if *int1 != val {
-				t.Errorf("Test %s failed with %s: int1: wanted [%d], got [%d]", t.Name(), testArgs[1], val, *int1)
-				return
-			}
-		}
-		if cmd2.Happened() {
-			if *flag1 != false {
-				t.Errorf("Test %s failed with %s: flag1: wanted [false], got [true]", t.Name(), testArgs[1])
-				return
-			}
-			if *string1 != "" {
-				t.Errorf("Test %s failed with %s: string1: wanted [], got [%s]", t.Name(), testArgs[1], *string1)
-				return
-			}
-			if *int1 != 0 {
-				t.Errorf("Test %s failed with %s: int1: wanted [0], got [%d]", t.Name(), testArgs[1], *int1)
-				return
-			}
+func TestIPNetFail1(t *testing.T) {
+	testArgs := []string{"progname", "--subnet", "10.0.0.0/40"}
+
+	p := NewParser("", "description")
+	_ = p.IPNet("s", "subnet", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-s|--subnet] is not a valid CIDR network, got \"10.0.0.0/40\""
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestRegexpSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--match", "^a+b$", "--any", ""}
+
+	p := NewParser("", "description")
+	match := p.Regexp("m", "match", nil)
+	any := p.Regexp("", "any", nil)
+	def := p.Regexp("", "default", &Options{Default: "[0-9]+"})
+	unset := p.Regexp("", "unset", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *match == nil || !(*match).MatchString("aab") || (*match).MatchString("abc") {
+		t.Errorf("Test %s failed. Want: [%s], got: [%v]", t.Name(), "^a+b$", *match)
+	}
+
+	if *any == nil || (*any).String() != "" {
+		t.Errorf("Test %s failed. Want empty expression, got: [%v]", t.Name(), *any)
+	}
+
+	if *def == nil || (*def).String() != "[0-9]+" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%v]", t.Name(), "[0-9]+", *def)
+	}
+
+	if *unset != nil {
+		t.Errorf("Test %s failed. Want: [nil], got: [%v]", t.Name(), *unset)
+	}
+}
+
+func TestRegexpFail1(t *testing.T) {
+	testArgs := []string{"progname", "--match", "(a"}
+
+	p := NewParser("", "description")
+	_ = p.Regexp("m", "match", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-m|--match] error parsing regexp: missing closing ): `(a`"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestJSONSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--config", `{"name":"test","ports":[80,443]}`}
+
+	var config struct {
+		Name  string `json:"name"`
+		Ports []int  `json:"ports"`
+	}
+	var defaults map[string]int
+
+	p := NewParser("progname", "description")
+	raw := p.JSON("c", "config", &config, nil)
+	_ = p.JSON("", "defaults", &defaults, &Options{Default: `{"a":1}`})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *raw != testArgs[2] {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), testArgs[2], *raw)
+	}
+
+	if config.Name != "test" || !reflect.DeepEqual(config.Ports, []int{80, 443}) {
+		t.Errorf("Test %s failed. Got: [%+v]", t.Name(), config)
+	}
+
+	if !reflect.DeepEqual(defaults, map[string]int{"a": 1}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), map[string]int{"a": 1}, defaults)
+	}
+
+	want := "usage: progname [-h|--help] [-c|--config <json>]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestJSONFail1(t *testing.T) {
+	testArgs := []string{"progname", "--config", "{a:1}"}
+
+	var config map[string]int
+
+	p := NewParser("", "description")
+	_ = p.JSON("c", "config", &config, nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-c|--config] invalid character 'a' looking for beginning of object key string"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestJSONTargetPanic1(t *testing.T) {
+	p := NewParser("", "description")
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Test %s failed. Expected panic for non-pointer target", t.Name())
 		}
-		if (cmd1.Happened() && cmd2.Happened()) || (!cmd1.Happened() && !cmd2.Happened()) {
-			t.Errorf("Test %s failed, either cmd1 and cmd2 or neither of them Happened()", t.Name())
+	}()
+
+	var config map[string]int
+	_ = p.JSON("c", "config", config, nil)
+}
+
+func TestFileSimple1(t *testing.T) {
+	// Test file location
+	fpath := "./test.tmp"
+	// Create test file
+	f, err := os.Create(fpath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f.Close()
+	defer os.Remove(fpath)
+
+	testArgs := []string{"progname", "-f", fpath}
+
+	p := NewParser("", "")
+
+	file1 := p.File("f", "file", os.O_RDWR, 0666, &Options{Default: "./non-existent-file.tmp"})
+
+	err = p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	defer file1.Close()
+
+	if file1 == nil {
+		t.Errorf("Test %s failed with file1 being nil pointer", t.Name())
+		return
+	}
+
+	testString := "Test"
+	recSlice := make([]byte, 4)
+	_, err = file1.WriteString(testString)
+	if err != nil {
+		t.Errorf("Test %s write operation failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	file1.Seek(0, 0)
+	n, err := file1.Read(recSlice)
+	if err != nil {
+		t.Errorf("Test %s read operation failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	if n != 4 || string(recSlice) != testString {
+		t.Errorf("Test %s failed on read operation", t.Name())
+		return
+	}
+}
+
+func TestFileMkdirAll1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "argparse")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "a", "b", "out.log")
+	p := NewParser("", "")
+	file1 := p.File("o", "out", os.O_RDWR|os.O_CREATE, 0666, &Options{MkdirAll: true, DirPerm: 0700})
+
+	if err := p.Parse([]string{"progname", "-o", fpath}); err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	defer file1.Close()
+
+	info, err := os.Stat(filepath.Join(dir, "a"))
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	if !info.IsDir() || info.Mode().Perm() != 0700 {
+		t.Errorf("Test %s failed with directory mode %s", t.Name(), info.Mode())
+	}
+
+	// Parent path taken by a regular file cannot be created
+	p = NewParser("", "")
+	p.File("o", "out", os.O_RDWR|os.O_CREATE, 0666, &Options{MkdirAll: true})
+	err = p.Parse([]string{"progname", "-o", filepath.Join(fpath, "x.log")})
+	if err == nil || !strings.HasPrefix(err.Error(), "[-o|--out] cannot create directory for ") {
+		t.Errorf("Test %s expected directory error, got %v", t.Name(), err)
+	}
+}
+
+func TestFileStdStreams1(t *testing.T) {
+	p := NewParser("", "")
+	in := p.File("i", "in", os.O_RDONLY, 0600, &Options{AllowStdStreams: true})
+	out := p.File("o", "out", os.O_WRONLY|os.O_CREATE, 0600, &Options{AllowStdStreams: true})
+
+	if err := p.Parse([]string{"progname", "-i", "-", "-o", "-"}); err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	if in.Fd() != os.Stdin.Fd() {
+		t.Errorf("Test %s expected stdin, got %s", t.Name(), in.Name())
+	}
+	if out.Fd() != os.Stdout.Fd() {
+		t.Errorf("Test %s expected stdout, got %s", t.Name(), out.Name())
+	}
+
+	// Without the option "-" is a file name
+	p = NewParser("", "")
+	p.File("i", "in", os.O_RDONLY, 0600, nil)
+	if err := p.Parse([]string{"progname", "-i", "-"}); err == nil {
+		t.Errorf("Test %s expected error opening file \"-\"", t.Name())
+	}
+}
+
+func TestFileListSimple1(t *testing.T) {
+	// Test file locations
+	fpaths := []string{"./test1.tmp", "./test2.tmp"}
+	// Create test files
+	for i, fpath := range fpaths {
+		err := ioutil.WriteFile(fpath, []byte(strconv.Itoa(i)), 0666)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer os.Remove(fpath)
+	}
+
+	testArgs := []string{"progname", "-f", fpaths[0], "--file", fpaths[1]}
+
+	p := NewParser("", "")
+
+	files := p.FileList("f", "file", os.O_RDONLY, 0666, nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if len(*files) != 2 {
+		t.Errorf("Test %s failed. Want: [2] files, got: [%d]", t.Name(), len(*files))
+		return
+	}
+
+	for i := range *files {
+		content, err := ioutil.ReadAll(&(*files)[i])
+		(*files)[i].Close()
+		if err != nil {
+			t.Errorf("Test %s read operation failed with error: %s", t.Name(), err.Error())
 			return
 		}
+		if string(content) != strconv.Itoa(i) {
+			t.Errorf("Test %s failed. Want: [%d], got: [%s]", t.Name(), i, content)
+		}
+	}
+}
+
+func TestFileListFail1(t *testing.T) {
+	// Test file location
+	fpath := "./test1.tmp"
+	// Create test file
+	f, err := os.Create(fpath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f.Close()
+	defer os.Remove(fpath)
+
+	testArgs := []string{"progname", "-f", fpath, "-f", "./non-existent-file.tmp"}
+
+	p := NewParser("", "")
+
+	files := p.FileList("f", "file", os.O_RDONLY, 0666, nil)
+
+	err = p.Parse(testArgs)
+	if err == nil || !strings.Contains(err.Error(), "non-existent-file.tmp") {
+		t.Errorf("Test %s expected error naming the path, got [%+v]", t.Name(), err)
+		return
+	}
+
+	// Files opened before the failure are closed and must not be left in result
+	if len(*files) != 0 {
+		t.Errorf("Test %s failed. Want: [0] files, got: [%d]", t.Name(), len(*files))
+	}
+
+	p = NewParser("", "")
+	files = p.FileList("f", "file", os.O_RDONLY, 0666, &Options{Default: []string{fpath, "./non-existent-file.tmp"}})
+
+	err = p.Parse([]string{"progname"})
+	if err == nil || !strings.Contains(err.Error(), "non-existent-file.tmp") {
+		t.Errorf("Test %s expected error naming the path, got [%+v]", t.Name(), err)
+		return
+	}
+	if len(*files) != 0 {
+		t.Errorf("Test %s failed. Want: [0] default files, got: [%d]", t.Name(), len(*files))
+	}
+}
+
+func TestListSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--flag-arg1", "test1", "--flag-arg1", "test2"}
+	list1Expect := []string{"test1", "test2"}
+	list2Expect := make([]string, 0)
+
+	p := NewParser("", "description")
+	l1 := p.List("f", "flag-arg1", nil)
+	l2 := p.List("", "flag-arg2", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if l1 == nil {
+		t.Errorf("Test %s failed with l1 being nil pointer", t.Name())
+		return
+	}
+
+	if l2 == nil {
+		t.Errorf("Test %s failed with l2 being nil pointer", t.Name())
+		return
+	}
+
+	if !reflect.DeepEqual(*l1, list1Expect) {
+		t.Errorf("Test %s failed. Want: %s, got: %s", t.Name(), list1Expect, *l1)
+		return
+	}
+
+	if !reflect.DeepEqual(*l2, list2Expect) {
+		t.Errorf("Test %s failed. Want: %s, got: %s", t.Name(), list2Expect, *l2)
+		return
+	}
+}
+
+func TestSelectorSimple1(t *testing.T) {
+	flag1Expect := "test2"
+	allowedValues := []string{"test1", flag1Expect}
+	testArgs := []string{"progname", "--flag-arg1", flag1Expect}
+
+	p := NewParser("", "")
+	s1 := p.Selector("f", "flag-arg1", allowedValues, nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if s1 == nil {
+		t.Errorf("Test %s failed with s1 being nil pointer", t.Name())
+		return
+	}
+
+	if *s1 != flag1Expect {
+		t.Errorf("Test %s failed. Want: %s, got: %s", t.Name(), flag1Expect, *s1)
+		return
+	}
+}
+
+func TestSelectorFailSimple1(t *testing.T) {
+	allowedValues := []string{"test1", "test2"}
+	testArgs := []string{"progname", "--flag-arg1", "test3"}
+
+	p := NewParser("", "")
+	_ = p.Selector("f", "flag-arg1", allowedValues, nil)
+
+	err := p.Parse(testArgs)
+	if err == nil {
+		t.Errorf("Test %s failed. Expected error did not happen", t.Name())
+		return
+	}
+}
+
+func TestSelectorCaseInsensitive1(t *testing.T) {
+	testArgs := []string{"progname", "--level", "Debug"}
+
+	p := NewParser("", "description")
+	s1 := p.Selector("l", "level", []string{"info", "debug"}, &Options{CaseInsensitive: true})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *s1 != "debug" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "debug", *s1)
+	}
+}
+
+func TestSelectorCaseInsensitiveFail1(t *testing.T) {
+	testArgs := []string{"progname", "--level", "Debug"}
+
+	p := NewParser("", "description")
+	_ = p.Selector("l", "level", []string{"info", "debug"}, nil)
+
+	err := p.Parse(testArgs)
+	errStr := "bad value for [-l|--level]. Allowed values are [info debug]"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestSelectorIndexSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--level", "DEBUG"}
+
+	p := NewParser("", "description")
+	i1 := p.SelectorIndex("l", "level", []string{"info", "debug", "trace"}, &Options{CaseInsensitive: true})
+	i2 := p.SelectorIndex("m", "mode", []string{"fast", "slow"}, nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *i1 != 1 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 1, *i1)
+	}
+
+	if *i2 != -1 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), -1, *i2)
+	}
+}
+
+func TestSelectorIndexFail1(t *testing.T) {
+	testArgs := []string{"progname", "--mode", "medium"}
+
+	p := NewParser("", "description")
+	_ = p.SelectorIndex("m", "mode", []string{"fast", "slow"}, nil)
+
+	err := p.Parse(testArgs)
+	errStr := "bad value for [-m|--mode]. Allowed values are [fast slow]"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestSelectorErrorFormatter1(t *testing.T) {
+	testArgs := []string{"progname", "--level", "trace"}
+
+	formatter := func(name string, allowed []string) error {
+		return fmt.Errorf("%s must be one of: %s", name, strings.Join(allowed, ", "))
+	}
+	p := NewParser("", "description")
+	level := p.Selector("l", "level", []string{"info", "debug"}, &Options{ErrorFormatter: formatter})
+
+	err := p.Parse(testArgs)
+	errStr := "-l|--level must be one of: info, debug"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	if *level != "" {
+		t.Errorf("Test %s failed. Want: [], got: [%s]", t.Name(), *level)
+	}
+}
+
+func TestChoiceSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--mode", "slow"}
+
+	p := NewParser("progname", "description")
+	modes := map[string]interface{}{"fast": time.Millisecond, "slow": time.Second}
+	c1 := p.Choice("m", "mode", modes, nil)
+	c2 := p.Choice("", "other", modes, &Options{Default: "fast"})
+	c3 := p.Choice("", "unset", modes, nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *c1 != time.Second {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), time.Second, *c1)
+	}
+
+	if *c2 != time.Millisecond {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), time.Millisecond, *c2)
+	}
+
+	if *c3 != nil {
+		t.Errorf("Test %s failed. Want: [nil], got: [%v]", t.Name(), *c3)
+	}
+
+	want := "usage: progname [-h|--help] [-m|--mode (fast|slow)]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestChoiceFail1(t *testing.T) {
+	testArgs := []string{"progname", "--mode", "medium"}
+
+	p := NewParser("", "description")
+	_ = p.Choice("m", "mode", map[string]interface{}{"slow": 2, "fast": 1, "auto": 0}, nil)
+
+	err := p.Parse(testArgs)
+	errStr := "bad value for [-m|--mode]. Allowed values are [auto fast slow]"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestCommandSimple1(t *testing.T) {
+	val := 5150
+	testArgsList := [][]string{
+		{"progname", "cmd1", "--flag1", "--string-flag1", "test", "--int-flag1", strconv.Itoa(val)},
+		{"progname", "cmd2"},
+	}
+
+	for _, testArgs := range testArgsList {
+		p := NewParser("progname", "description")
+
+		cmd1 := p.NewCommand("cmd1", "cmd1 description")
+		flag1 := cmd1.Flag("f", "flag1", nil)
+		string1 := cmd1.String("s", "string-flag1", nil)
+		int1 := cmd1.Int("i", "int-flag1", nil)
+
+		cmd2 := p.NewCommand("cmd2", "cmd2 description")
+
+		p.Parse(testArgs)
+
+		if cmd1.Happened() {
+			if *flag1 != true {
+				t.Errorf("Test %s failed with %s: flag1: wanted [true], got [false]", t.Name(), testArgs[1])
+				return
+			}
+			if *string1 != "test" {
+				t.Errorf("Test %s failed with %s: string1: wanted [test], got [%s]", t.Name(), testArgs[1], *string1)
+				return
+			}
+			if *int1 != val {
+				t.Errorf("Test %s failed with %s: int1: wanted [%d], got [%d]", t.Name(), testArgs[1], val, *int1)
+				return
+			}
+		}
+		if cmd2.Happened() {
+			if *flag1 != false {
+				t.Errorf("Test %s failed with %s: flag1: wanted [false], got [true]", t.Name(), testArgs[1])
+				return
+			}
+			if *string1 != "" {
+				t.Errorf("Test %s failed with %s: string1: wanted [], got [%s]", t.Name(), testArgs[1], *string1)
+				return
+			}
+			if *int1 != 0 {
+				t.Errorf("Test %s failed with %s: int1: wanted [0], got [%d]", t.Name(), testArgs[1], *int1)
+				return
+			}
+		}
+		if (cmd1.Happened() && cmd2.Happened()) || (!cmd1.Happened() && !cmd2.Happened()) {
+			t.Errorf("Test %s failed, either cmd1 and cmd2 or neither of them Happened()", t.Name())
+			return
+		}
+	}
+}
+
+func TestCommandAlias1(t *testing.T) {
+	for _, name := range []string{"remove", "rm", "delete"} {
+		p := NewParser("progname", "description")
+		remove := p.NewCommand("remove", "Remove an item")
+		remove.AddAlias("rm")
+		remove.AddAlias("delete")
+		force := remove.Flag("f", "force", nil)
+		list := p.NewCommand("list", "List items")
+
+		err := p.Parse([]string{"progname", name, "-f"})
+		if err != nil {
+			t.Errorf("Test %s failed with %s: %s", t.Name(), name, err.Error())
+			return
+		}
+
+		if !remove.Happened() || list.Happened() || !*force {
+			t.Errorf("Test %s failed with %s: [remove] command was not invoked", t.Name(), name)
+		}
+	}
+}
+
+func TestCommandAliasUsage1(t *testing.T) {
+	p := NewParser("progname", "description")
+	remove := p.NewCommand("remove", "Remove an item")
+	remove.AddAlias("rm")
+	remove.AddAlias("delete")
+	_ = p.NewCommand("list", "List items")
+
+	want := "Commands:\n\n  remove (rm, delete)  Remove an item\n  list                 List items\n"
+	if usage := p.Usage(nil); !strings.Contains(usage, want) {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestCommandAliasCollision1(t *testing.T) {
+	p := NewParser("progname", "description")
+	remove := p.NewCommand("remove", "Remove an item")
+	_ = p.NewCommand("list", "List items")
+
+	defer func() {
+		r := recover()
+		want := "argparse: alias [list] of [remove] command collides with [list] command"
+		if r != want {
+			t.Errorf("Test %s failed. Want panic: [%s], got: [%v]", t.Name(), want, r)
+		}
+	}()
+
+	remove.AddAlias("list")
+}
+
+func TestCommandAliasCollision2(t *testing.T) {
+	p := NewParser("progname", "description")
+	remove := p.NewCommand("remove", "Remove an item")
+	remove.AddAlias("rm")
+
+	defer func() {
+		r := recover()
+		want := "argparse: [rm] command collides with [remove] command"
+		if r != want {
+			t.Errorf("Test %s failed. Want panic: [%s], got: [%v]", t.Name(), want, r)
+		}
+	}()
+
+	_ = p.NewCommand("rm", "Remove files")
+}
+
+func TestCommandNested1(t *testing.T) {
+	testArgs := []string{"progname", "remote", "add", "--fetch", "origin", "-v"}
+
+	p := NewParser("progname", "description")
+	verbose := p.Flag("v", "verbose", nil)
+	remote := p.NewCommand("remote", "Manage remotes")
+	add := remote.NewCommand("add", "Add a remote")
+	fetch := add.Flag("f", "fetch", nil)
+	name := add.Positional("name", &Options{Required: true})
+	remove := remote.NewCommand("remove", "Remove a remote")
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !remote.Happened() || !add.Happened() || remove.Happened() {
+		t.Errorf("Test %s failed. Wrong commands were invoked", t.Name())
+	}
+
+	if !*verbose || !*fetch || *name != "origin" {
+		t.Errorf("Test %s failed. Got: verbose [%t], fetch [%t], name [%s]", t.Name(), *verbose, *fetch, *name)
+	}
+}
+
+func TestCommandNestedHelp1(t *testing.T) {
+	// Capture usage printed to stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	p := NewParser("progname", "description")
+	p.ExitFunc = func(int) {}
+	_ = p.Flag("v", "verbose", nil)
+	remote := p.NewCommand("remote", "Manage remotes")
+	_ = remote.NewCommand("add", "Add a remote")
+	_ = remote.NewCommand("remove", "Remove a remote")
+
+	err = p.Parse([]string{"progname", "remote", "--help"})
+	w.Close()
+	output, _ := ioutil.ReadAll(r)
+	if err != ErrHelpRequested {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), ErrHelpRequested, err)
+	}
+
+	if usage := remote.Usage(nil); string(output) != usage {
+		t.Errorf("Test %s failed. Want usage of [remote], got: [%s]", t.Name(), output)
+	}
+
+	if !strings.Contains(string(output), "  add     Add a remote\n  remove  Remove a remote\n") {
+		t.Errorf("Test %s failed. Usage does not list sub-commands: [%s]", t.Name(), output)
+	}
+}
+
+func TestCommandDisableInheritance1(t *testing.T) {
+	p := NewParser("progname", "description")
+	p.DisableInheritance = true
+	verbose := p.Flag("v", "verbose", nil)
+	level := p.Int("l", "level", &Options{Default: 2})
+	remote := p.NewCommand("remote", "Manage remotes")
+	add := remote.NewCommand("add", "Add a remote")
+	fetch := add.Flag("f", "fetch", nil)
+
+	err := p.Parse([]string{"progname", "remote", "add", "-f", "-v"})
+	errStr := "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	if *verbose || !*fetch || *level != 2 {
+		t.Errorf("Test %s failed. Got: verbose [%t], fetch [%t], level [%d]", t.Name(), *verbose, *fetch, *level)
+	}
+
+	want := "usage: progname remote add [-f|--fetch] [-h|--help]"
+	if usage := add.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestCommandMixedArgs1(t *testing.T) {
+	val := 5150
+	pval := 316
+	testArgsList := [][]string{
+		{"progname", "cmd1", "--flag1", "--string-flag1", "test", "--int-flag1", strconv.Itoa(val), "--global-flag", "--global-string", "global test string", "--global-int", strconv.Itoa(pval)},
+		{"progname", "cmd2", "--global-string", "global test string", "--global-flag", "--global-int", strconv.Itoa(pval)},
+	}
+
+	for _, testArgs := range testArgsList {
+		p := NewParser("progname", "description")
+
+		cmd1 := p.NewCommand("cmd1", "cmd1 description")
+		cmd1flag1 := cmd1.Flag("f", "flag1", nil)
+		cmd1string1 := cmd1.String("s", "string-flag1", nil)
+		cmd1int1 := cmd1.Int("i", "int-flag1", nil)
+
+		cmd2 := p.NewCommand("cmd2", "cmd2 description")
+
+		pflag1 := p.Flag("", "global-flag", nil)
+		pstring1 := p.String("", "global-string", nil)
+		pint1 := p.Int("", "global-int", nil)
+
+		p.Parse(testArgs)
+
+		// Check global flags
+		if *pflag1 != true {
+			t.Errorf("Test %s failed with %s: pflag1: wanted [true], got [false]", t.Name(), testArgs[1])
+			return
+		}
+		if *pstring1 != "global test string" {
+			t.Errorf("Test %s failed with %s: pstring1: wanted [global test string], got [%s]", t.Name(), testArgs[1], *pstring1)
+			return
+		}
+		if *pint1 != pval {
+			t.Errorf("Test %s failed with %s: pint1: wanted [%d], got [%d]", t.Name(), testArgs[1], pval, *pint1)
+			return
+		}
+
+		// Check commands
+		if cmd1.Happened() {
+			if *cmd1flag1 != true {
+				t.Errorf("Test %s failed with %s: flag1: wanted [true], got [false]", t.Name(), testArgs[1])
+				return
+			}
+			if *cmd1string1 != "test" {
+				t.Errorf("Test %s failed with %s: string1: wanted [test], got [%s]", t.Name(), testArgs[1], *cmd1string1)
+				return
+			}
+			if *cmd1int1 != val {
+				t.Errorf("Test %s failed with %s: int1: wanted [%d], got [%d]", t.Name(), testArgs[1], val, *cmd1int1)
+				return
+			}
+		}
+		if cmd2.Happened() {
+			if *cmd1flag1 != false {
+				t.Errorf("Test %s failed with %s: flag1: wanted [false], got [true]", t.Name(), testArgs[1])
+				return
+			}
+			if *cmd1string1 != "" {
+				t.Errorf("Test %s failed with %s: string1: wanted [], got [%s]", t.Name(), testArgs[1], *cmd1string1)
+				return
+			}
+			if *cmd1int1 != 0 {
+				t.Errorf("Test %s failed with %s: int1: wanted [0], got [%d]", t.Name(), testArgs[1], *cmd1int1)
+				return
+			}
+		}
+		if (cmd1.Happened() && cmd2.Happened()) || (!cmd1.Happened() && !cmd2.Happened()) {
+			t.Errorf("Test %s failed, either cmd1 and cmd2 or neither of them Happened()", t.Name())
+			return
+		}
+	}
+}
+
+func TestOptsRequired1(t *testing.T) {
+	testArgs := []string{"progname", "--flag-arg1"}
+
+	p := NewParser("", "description")
+	_ = p.Flag("", "flag-arg1", nil)
+	_ = p.String("", "flag-arg2", &Options{Required: true})
+
+	err := p.Parse(testArgs)
+	if err == nil {
+		t.Errorf("Test %s failed to detect required argument", t.Name())
+		return
+	}
+}
+
+func TestOptsRequired2(t *testing.T) {
+	testArgs := []string{"progname", "--flag-arg1"}
+
+	p := NewParser("", "description")
+	_ = p.Flag("", "flag-arg1", nil)
+	_ = p.Int("", "int-arg1", &Options{Required: true})
+
+	err := p.Parse(testArgs)
+	if err == nil {
+		t.Errorf("Test %s failed to detect required argument", t.Name())
+		return
+	}
+}
+
+var stropts = &Options{Validate: func(args []string) error {
+	if len(args) > 0 {
+		if args[0] != "pass" {
+			return errors.New("failure")
+		}
+	}
+	return nil
+},
+}
+
+func TestOptsValidatePass1(t *testing.T) {
+	testArgsList := [][]string{
+		{"progname", "--string-flag1", "pass"},
+		{"progname", "--string-flag1", "fail"},
+	}
+
+	for _, testArgs := range testArgsList {
+		p := NewParser("progname", "")
+
+		string1 := p.String("", "string-flag1", stropts)
+
+		err := p.Parse(testArgs)
+
+		if testArgs[2] == "pass" {
+			if err != nil {
+				t.Errorf("Test %s failed on %s with err: %s", t.Name(), testArgs[2], err.Error())
+				return
+			}
+
+			if *string1 != "pass" {
+				t.Errorf("Test %s failed on %s; string1 expected [%s], got [%s]", t.Name(), testArgs[2], testArgs[2], *string1)
+				return
+			}
+		} else {
+			if err == nil {
+				t.Errorf("Test %s failed to validate argument (should return error)", t.Name())
+				return
+			}
+		}
+	}
+}
+
+func TestOptsValidatePass2(t *testing.T) {
+	val1 := 5150
+	val2 := 316
+
+	var intopts = &Options{Validate: func(args []string) error {
+		if len(args) > 0 {
+			myval, err := strconv.Atoi(args[0])
+			if err != nil {
+				return errors.New("conversion failure")
+			} else if myval != val1 {
+				return errors.New("failure")
+			}
+		}
+		return nil
+	},
+	}
+
+	testArgsList := [][]string{
+		{"progname", "--int-flag1", strconv.Itoa(val1)},
+		{"progname", "--int-flag1", strconv.Itoa(val2)},
+	}
+
+	for _, testArgs := range testArgsList {
+		p := NewParser("progname", "")
+
+		int1 := p.Int("", "int-flag1", intopts)
+
+		err := p.Parse(testArgs)
+
+		if testArgs[2] == strconv.Itoa(val1) {
+			if err != nil {
+				t.Errorf("Test %s failed on %s with err: %s", t.Name(), testArgs[2], err.Error())
+				return
+			}
+
+			if *int1 != val1 {
+				t.Errorf("Test %s failed on %s; int1 expected [%d], got [%d]", t.Name(), testArgs[2], val1, *int1)
+				return
+			}
+		} else {
+			if err == nil {
+				t.Errorf("Test %s failed to validate argument (should return error)", t.Name())
+				return
+			}
+		}
+	}
+}
+
+func TestOptsValidators1(t *testing.T) {
+	errEmpty := errors.New("empty")
+	errShort := errors.New("too short")
+	calls := make([]string, 0)
+
+	opts := &Options{
+		Validate: func(args []string) error {
+			calls = append(calls, "validate")
+			return nil
+		},
+		Validators: []func(args []string) error{
+			func(args []string) error {
+				calls = append(calls, "empty")
+				if args[0] == "" {
+					return errEmpty
+				}
+				return nil
+			},
+			func(args []string) error {
+				calls = append(calls, "short")
+				if len(args[0]) < 3 {
+					return errShort
+				}
+				return nil
+			},
+			func(args []string) error {
+				calls = append(calls, "last")
+				return nil
+			},
+		},
+	}
+
+	p := NewParser("progname", "")
+	_ = p.String("s", "string", opts)
+
+	err := p.Parse([]string{"progname", "-s", "ab"})
+	if err != errShort {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errShort, err)
+	}
+
+	want := []string{"validate", "empty", "short"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Test %s failed. Want calls: [%v], got: [%v]", t.Name(), want, calls)
+	}
+
+	p = NewParser("progname", "")
+	s := p.String("s", "string", opts)
+
+	err = p.Parse([]string{"progname", "-s", "abc"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *s != "abc" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "abc", *s)
+	}
+}
+
+var pUsage = `usage: verylongprogname <Command> [-h|--help] [-s|--verylongstring-flag1
+                        "<value>"] [-i|--integer-flag1 <integer>]
+
+                        prog description
+
+Commands:
+
+  veryverylongcmd1  cmd1 description
+  cmd2              cmd2 description
+
+Arguments:
+
+  -h  --help                  Print help information
+  -s  --verylongstring-flag1  string1 description
+  -i  --integer-flag1         integer1 description
+
+`
+
+var cmd1Usage = `usage: verylongprogname veryverylongcmd1 [-f|--verylongflag1]
+                        -a|--verylongflagA [-h|--help]
+                        [-s|--verylongstring-flag1 "<value>"]
+                        [-i|--integer-flag1 <integer>]
+
+                        cmd1 description
+
+Arguments:
+
+  -f  --verylongflag1         flag1 description
+  -a  --verylongflagA         flag1 description
+  -h  --help                  Print help information
+  -s  --verylongstring-flag1  string1 description
+  -i  --integer-flag1         integer1 description
+
+`
+
+var cmd2Usage = `usage: verylongprogname cmd2 [-h|--help] [-s|--verylongstring-flag1 "<value>"]
+                        [-i|--integer-flag1 <integer>]
+
+                        cmd2 description
+
+Arguments:
+
+  -h  --help                  Print help information
+  -s  --verylongstring-flag1  string1 description
+  -i  --integer-flag1         integer1 description
+
+`
+
+func TestUsageSimple1(t *testing.T) {
+	p := NewParser("verylongprogname", "prog description")
+
+	cmd1 := p.NewCommand("veryverylongcmd1", "cmd1 description")
+	_ = cmd1.Flag("f", "verylongflag1", &Options{Help: "flag1 description"})
+	_ = cmd1.Flag("a", "verylongflagA", &Options{Required: true, Help: "flag1 description"})
+	_ = p.String("s", "verylongstring-flag1", &Options{Help: "string1 description"})
+	_ = p.Int("i", "integer-flag1", &Options{Help: "integer1 description"})
+
+	cmd2 := p.NewCommand("cmd2", "cmd2 description")
+
+	p.Parse(os.Args)
+
+	if pUsage != p.Usage(nil) {
+		t.Errorf("%s", p.Usage(nil))
+	}
+	if cmd1Usage != cmd1.Usage(nil) {
+		t.Errorf("%s", cmd1.Usage(nil))
+	}
+	if cmd2Usage != cmd2.Usage(nil) {
+		t.Errorf("%s", cmd2.Usage(nil))
+	}
+}
+
+func TestUsageHidden1(t *testing.T) {
+	p := NewParser("verylongprogname", "prog description")
+
+	cmd1 := p.NewCommand("veryverylongcmd1", "cmd1 description")
+	_ = cmd1.Flag("f", "verylongflag1", &Options{Help: "flag1 description"})
+	_ = cmd1.Flag("a", "verylongflagA", &Options{Required: true, Help: "flag1 description"})
+	_ = p.String("s", "verylongstring-flag1", &Options{Help: "string1 description"})
+	_ = p.Int("i", "integer-flag1", &Options{Help: "integer1 description"})
+	_ = p.Int("i2", "integer-flag2", &Options{Help: DisableDescription})
+
+	_ = p.NewCommand("cmd2", "cmd2 description")
+
+	cmd3 := p.NewCommand("cmd3", DisableDescription)
+	_ = cmd3.Flag("f", "verylongflag1", &Options{Help: "flag1 description"})
+	_ = cmd3.Flag("a", "verylongflagA", &Options{Required: true, Help: "flag1 description"})
+
+	p.Parse(os.Args)
+
+	if pUsage != p.Usage(nil) {
+		t.Errorf("%s", p.Usage(nil))
+	}
+	if cmd1Usage != cmd1.Usage(nil) {
+		t.Errorf("%s", cmd1.Usage(nil))
+	}
+}
+
+func TestUsageHidden2(t *testing.T) {
+	p := NewParser("prog", "program description")
+	secret := p.String("", "experimental", &Options{Hidden: true, Help: "Not ready yet"})
+
+	if usage := p.Usage(nil); usage != pUsageString[len("test string\n"):] {
+		t.Errorf("%s", usage)
+	}
+
+	err := p.Parse([]string{"prog", "--experimental", "on"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *secret != "on" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "on", *secret)
+	}
+}
+
+func TestHiddenRequiredPanic1(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Test %s failed. Hidden required argument was accepted", t.Name())
+		}
+	}()
+
+	p := NewParser("prog", "program description")
+	_ = p.String("", "experimental", &Options{Hidden: true, Required: true})
+}
+
+func TestStringMissingArgFail(t *testing.T) {
+	testArgs := []string{"progname", "-s"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.String("s", "string", &Options{Required: true, Help: "A test string"})
+
+	err := p.Parse(testArgs)
+
+	if err != nil {
+		// Test should pass on failure
+		if err.Error() != "not enough arguments for -s|--string" {
+			t.Errorf("Test %s failed: expected error [%s], got error [%s]", t.Name(), "not enough arguments for -s|--string", err.Error())
+		}
+	}
+}
+
+func TestIntMissingArgFail(t *testing.T) {
+	testArgs := []string{"progname", "-i"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.Int("i", "integer", &Options{Required: true, Help: "A test integer"})
+
+	err := p.Parse(testArgs)
+
+	if err != nil {
+		// Test should pass on failure
+		errStr := "not enough arguments for -i|--integer"
+		if err.Error() != errStr {
+			t.Errorf("Test %s failed: expected error [%s], got error [%s]", t.Name(), errStr, err.Error())
+		}
+	}
+}
+
+func TestFlagDefaultValuePass(t *testing.T) {
+	testArgs := []string{"progname"}
+
+	p := NewParser("progname", "Prog description")
+
+	f := p.Flag("f", "flag", &Options{Default: true})
+
+	err := p.Parse(testArgs)
+
+	// Should fail on failure
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Should fail if not true
+	if *f != true {
+		t.Errorf("expected [true], got [%t]", *f)
+	}
+}
+
+func TestFlagDefaultValueFail(t *testing.T) {
+	testArgs := []string{"progname"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.Flag("f", "flag", &Options{Default: "string"})
+
+	err := p.Parse(testArgs)
+
+	// Should pass on failure
+	if err == nil || err.Error() != "cannot use default type [string] as type [bool]" {
+		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [string] as type [bool]", err)
+	}
+}
+
+func TestStringDefaultValuePass(t *testing.T) {
+	testArgs := []string{"progname"}
+	testString := "test string"
+
+	p := NewParser("progname", "Prog description")
+
+	s := p.String("s", "string", &Options{Default: testString})
+
+	err := p.Parse(testArgs)
+
+	// Should fail on failure
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Should fail if not true
+	if *s != testString {
+		t.Errorf("expected [string], got [%T]", *s)
+	}
+}
+
+func TestStringDefaultValueFail(t *testing.T) {
+	testArgs := []string{"progname"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.String("s", "string", &Options{Default: false})
+
+	err := p.Parse(testArgs)
+
+	// Should pass on failure
+	if err == nil || err.Error() != "cannot use default type [bool] as type [string]" {
+		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	}
+}
+
+func TestIntDefaultValuePass(t *testing.T) {
+	testArgs := []string{"progname"}
+	testVal := 5150
+
+	p := NewParser("progname", "Prog description")
+
+	i := p.Int("i", "integer", &Options{Default: testVal})
+
+	err := p.Parse(testArgs)
+
+	// Should fail on failure
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Should fail if not true
+	if *i != testVal {
+		t.Errorf("expected [%d], got [%d]", testVal, *i)
+	}
+}
+
+func TestIntDefaultValueFail(t *testing.T) {
+	testArgs := []string{"progname"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.Int("i", "integer", &Options{Default: "fail"})
+
+	err := p.Parse(testArgs)
+
+	// Should pass on failure
+	if err == nil || err.Error() != "cannot use default type [string] as type [int]" {
+		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	}
+}
+
+func TestFloatDefaultValuePass(t *testing.T) {
+	testArgs := []string{"progname"}
+	testVal := 0.05
+
+	p := NewParser("progname", "Prog description")
+
+	f := p.Float("f", "float", &Options{Default: testVal})
+
+	err := p.Parse(testArgs)
+
+	// Should fail on failure
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Should fail if not true
+	if *f != testVal {
+		t.Errorf("expected [%f], got [%f]", testVal, *f)
+	}
+}
+
+func TestFloatDefaultValueFail(t *testing.T) {
+	testArgs := []string{"progname"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.Float("f", "float", &Options{Default: 5})
+
+	err := p.Parse(testArgs)
+
+	// Should pass on failure
+	if err == nil || err.Error() != "cannot use default type [int] as type [float64]" {
+		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [int] as type [float64]", err)
+	}
+}
+
+func TestFileDefaultValuePass(t *testing.T) {
+	// Test file location
+	fpath := "./test.tmp"
+	// Create test file
+	f, err := os.Create(fpath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f.Close()
+	defer os.Remove(fpath)
+
+	testArgs := []string{"progname"}
+
+	p := NewParser("", "")
+
+	file1 := p.File("f", "file", os.O_RDWR, 0666, &Options{Default: fpath})
+
+	err = p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	defer file1.Close()
+}
+
+func TestFileDefaultValueFail(t *testing.T) {
+	// Test file location
+	fpath := "./test.tmp"
+	// Create test file
+	f, err := os.Create(fpath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f.Close()
+	defer os.Remove(fpath)
+
+	testArgs := []string{"progname"}
+
+	p := NewParser("", "")
+
+	file1 := p.File("f", "file", os.O_RDWR, 0666, &Options{Default: true})
+
+	err = p.Parse(testArgs)
+	if err == nil || err.Error() != "cannot use default type [bool] as type [string]" {
+		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	}
+	defer file1.Close()
+}
+
+func TestListDefaultValuePass(t *testing.T) {
+	testArgs := []string{"progname"}
+	testList := []string{"test", "list"}
+
+	p := NewParser("progname", "Prog description")
+
+	s := p.List("s", "string", &Options{Default: testList})
+
+	err := p.Parse(testArgs)
+
+	// Should fail on failure
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Should fail if not true
+	if !reflect.DeepEqual(*s, testList) {
+		t.Errorf("expected [%v], got [%v]", testList, *s)
+	}
+}
+
+func TestListDefaultValueFail(t *testing.T) {
+	testArgs := []string{"progname"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.List("s", "string", &Options{Default: false})
+
+	err := p.Parse(testArgs)
+
+	// Should pass on failure
+	if err == nil || err.Error() != "cannot use default type [bool] as type [[]string]" {
+		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [[]string]", err)
+	}
+}
+
+func TestSelectorDefaultValuePass(t *testing.T) {
+	testArgs := []string{"progname"}
+	testString := "test list"
+
+	p := NewParser("progname", "Prog description")
+
+	s := p.Selector("s", "string", []string{"opt1", "opt2"}, &Options{Default: testString})
+
+	err := p.Parse(testArgs)
+
+	// Should fail on failure
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Should fail if not true
+	if *s != testString {
+		t.Errorf("expected [%v], got [%v]", testString, *s)
+	}
+}
+
+func TestSelectorDefaultValueFail(t *testing.T) {
+	testArgs := []string{"progname"}
+
+	p := NewParser("progname", "Prog description")
+
+	_ = p.Selector("s", "string", []string{"opt1", "opt2"}, &Options{Default: false})
+
+	err := p.Parse(testArgs)
+
+	// Should pass on failure
+	if err == nil || err.Error() != "cannot use default type [bool] as type [string]" {
+		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	}
+}
+
+func TestWasSet1(t *testing.T) {
+	testArgs := []string{"progname", "cmd", "--name", "default"}
+
+	p := NewParser("progname", "Prog description")
+
+	name := p.String("n", "name", &Options{Default: "default"})
+	flag := p.Flag("f", "flag", nil)
+	cmd := p.NewCommand("cmd", "")
+	level := cmd.Int("l", "level", &Options{Default: 1})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !p.WasSet(name) {
+		t.Errorf("Test %s failed. [--name] was provided with its default value", t.Name())
+	}
+	if p.WasSet(flag) {
+		t.Errorf("Test %s failed. [--flag] was not provided", t.Name())
+	}
+	if p.WasSet(level) || cmd.WasSet(level) {
+		t.Errorf("Test %s failed. [--level] only got its default value", t.Name())
+	}
+}
+
+func TestWasSetUnknown1(t *testing.T) {
+	p := NewParser("progname", "Prog description")
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Test %s failed. Expected panic for unknown argument", t.Name())
+		}
+	}()
+
+	p.WasSet(new(string))
+}
+
+func TestFloatSimple1(t *testing.T) {
+	pi := "3.1415"
+	piVal := 3.1415
+	testArgs := []string{"progname", "--float1", pi}
+
+	p := NewParser("", "description")
+	f1 := p.Float("f", "float1", nil)
+	f2 := p.Float("", "float2", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if f1 == nil {
+		t.Errorf("Test %s failed with float1 being nil pointer", t.Name())
+		return
+	}
+
+	if f2 == nil {
+		t.Errorf("Test %s failed with flag2 being nil pointer", t.Name())
+		return
+	}
+
+	if *f1 != piVal {
+		t.Errorf("Test %s failed. Want: [%f], got: [%f]", t.Name(), piVal, *f1)
+		return
+	}
+
+	if *f2 != 0 {
+		t.Errorf("Test %s failed. Want: [%f], got: [%f]", t.Name(), 0.0, *f2)
+		return
+	}
+}
+
+func TestFloatFail1(t *testing.T) {
+	badArg := "stringNotANumber"
+	testArgs := []string{"progname", "--float1", badArg}
+
+	p := NewParser("", "description")
+	f1 := p.Float("f", "float1", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-f|--float1] bad floating point value [stringNotANumber]"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		return
+	}
+
+	if f1 == nil {
+		t.Errorf("Test %s failed with float1 being nil pointer", t.Name())
+		return
+	}
+
+	if *f1 != 0 {
+		t.Errorf("Test %s failed. Want: [0], got: [%f]", t.Name(), *f1)
+		return
+	}
+}
+
+func TestFloatNegative1(t *testing.T) {
+	testArgs := []string{"progname", "--threshold", "-0.05"}
+
+	p := NewParser("", "description")
+	f1 := p.Float("t", "threshold", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *f1 != -0.05 {
+		t.Errorf("Test %s failed. Want: [%f], got: [%f]", t.Name(), -0.05, *f1)
+		return
+	}
+}
+
+func TestFloatExponent1(t *testing.T) {
+	testArgs := []string{"progname", "--threshold", "1.5e-3"}
+
+	p := NewParser("", "description")
+	f1 := p.Float("t", "threshold", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *f1 != 0.0015 {
+		t.Errorf("Test %s failed. Want: [%f], got: [%f]", t.Name(), 0.0015, *f1)
+		return
+	}
+}
+
+func TestFloatTooManyArgsFail1(t *testing.T) {
+	p := NewParser("", "description")
+	_ = p.Float("t", "threshold", nil)
+
+	// Parse never hands more than one value to a float, so exercise the argument directly
+	a := p.args[len(p.args)-1]
+	err := a.parse([]string{"0.5", "0.7"})
+	errStr := "[-t|--threshold] followed by too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		return
+	}
+}
+
+func TestEqualsValue1(t *testing.T) {
+	testArgs := []string{"progname", "--file=out.txt", "-o=value", "--count=5"}
+
+	p := NewParser("", "description")
+	s1 := p.String("f", "file", nil)
+	s2 := p.String("o", "output", nil)
+	i1 := p.Int("c", "count", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *s1 != "out.txt" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "out.txt", *s1)
+	}
+
+	if *s2 != "value" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "value", *s2)
+	}
+
+	if *i1 != 5 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 5, *i1)
+	}
+}
+
+func TestEqualsValueEmpty1(t *testing.T) {
+	testArgs := []string{"progname", "--file="}
+
+	p := NewParser("", "description")
+	s1 := p.String("f", "file", &Options{Default: "default"})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *s1 != "" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "", *s1)
+	}
+}
+
+func TestEqualsValueContainsEquals1(t *testing.T) {
+	testArgs := []string{"progname", "--expr=a=b", "-l=c=d", "--list", "e=f"}
+
+	p := NewParser("", "description")
+	s1 := p.String("e", "expr", nil)
+	l1 := p.List("l", "list", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *s1 != "a=b" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "a=b", *s1)
+	}
+
+	if !reflect.DeepEqual(*l1, []string{"c=d", "e=f"}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), []string{"c=d", "e=f"}, *l1)
+	}
+}
+
+func TestEqualsValueFlagFail1(t *testing.T) {
+	testArgs := []string{"progname", "--verbose=foo"}
+
+	p := NewParser("", "description")
+	_ = p.Flag("v", "verbose", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-v|--verbose] does not take a value"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestEqualsValueFlag1(t *testing.T) {
+	testArgs := []string{"progname", "--color=false", "-b=0", "-i", "-u"}
+
+	p := NewParser("", "description")
+	color := p.Flag("c", "color", &Options{Default: true})
+	bold := p.Flag("b", "bold", &Options{Default: true})
+	italic := p.Flag("i", "italic", nil)
+	underline := p.Flag("u", "underline", &Options{Default: true})
+	strike := p.Flag("s", "strike", &Options{Default: true})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *color || *bold || !*italic || !*underline || !*strike {
+		t.Errorf("Test %s failed. Got: color [%t], bold [%t], italic [%t], underline [%t], strike [%t]",
+			t.Name(), *color, *bold, *italic, *underline, *strike)
+	}
+
+	p.Reset()
+	err = p.Parse([]string{"progname", "-us=false"})
+	errStr := "[-us] combined flags cannot take a value"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	p.Reset()
+	err = p.Parse([]string{"progname", "--italic=true", "-i"})
+	errStr = "[-i|--italic] can only be present once"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestFlagNegatable1(t *testing.T) {
+	testArgs := []string{"progname", "--no-color"}
+
+	p := NewParser("", "description")
+	f1 := p.Flag("c", "color", &Options{Negatable: true, Default: true})
+	f2 := p.Flag("b", "bold", &Options{Negatable: true, Default: true})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *f1 != false {
+		t.Errorf("Test %s failed with color being true", t.Name())
+	}
+
+	if *f2 != true {
+		t.Errorf("Test %s failed with bold being false", t.Name())
+	}
+}
+
+func TestFlagNegatableFail1(t *testing.T) {
+	testArgs := []string{"progname", "--no-color"}
+
+	p := NewParser("", "description")
+	_ = p.Flag("c", "color", nil)
+
+	err := p.Parse(testArgs)
+	if err == nil {
+		t.Errorf("Test %s failed. Negated form of non-negatable flag accepted", t.Name())
+	}
+}
+
+func TestFlagNegatableFail2(t *testing.T) {
+	testArgs := []string{"progname", "--color", "--no-color"}
+
+	p := NewParser("", "description")
+	_ = p.Flag("c", "color", &Options{Negatable: true})
+
+	err := p.Parse(testArgs)
+	errStr := "[-c|--color] can only be present once"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestFlagStacked1(t *testing.T) {
+	// Flag repeated in combined shorthand is accepted, and every occurrence of it counts
+	for _, testArgs := range [][]string{{"progname", "-vv"}, {"progname", "-vqv"}} {
+		p := NewParser("", "description")
+		verbose := p.Flag("v", "verbose", nil)
+		_ = p.Flag("q", "quiet", nil)
+
+		err := p.Parse(testArgs)
+		if err != nil {
+			t.Errorf("Test %s %v failed with error: %s", t.Name(), testArgs[1:], err.Error())
+			continue
+		}
+		if !*verbose || p.Occurrences(verbose) != 2 {
+			t.Errorf("Test %s %v failed. Got: verbose [%t] %d times", t.Name(), testArgs[1:], *verbose, p.Occurrences(verbose))
+		}
+	}
+}
+
+func TestFlagNegatableFail3(t *testing.T) {
+	p := NewParser("", "description")
+	p.Messages = map[string]string{"[%s] cannot be negated": "[%s] kann nicht verneint werden"}
+	_ = p.String("n", "name", &Options{Negatable: true})
+
+	err := p.Parse([]string{"progname", "--no-name"})
+	errStr := "[-n|--name] kann nicht verneint werden"
+	if perr, ok := err.(*ParseError); !ok || perr.Kind != KindBadValue || perr.Error() != errStr {
+		t.Errorf("Test %s expected ParseError [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestFlagNegatableUsage1(t *testing.T) {
+	p := NewParser("prog", "description")
+	_ = p.Flag("c", "color", &Options{Negatable: true})
+
+	want := "usage: prog [-h|--help] [-c|--color|--no-color]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestHelpDisableExit1(t *testing.T) {
+	testArgs := []string{"progname", "-s", "value", "--help"}
+
+	p := NewParser("", "description")
+	p.DisableHelpExit = true
+	_ = p.String("s", "string", nil)
+
+	err := p.Parse(testArgs)
+	if err != ErrHelpRequested {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), ErrHelpRequested, err)
+	}
+}
+
+func TestHelpDisableExit2(t *testing.T) {
+	testArgs := []string{"progname", "cmd", "-h"}
+
+	p := NewParser("", "description")
+	p.DisableHelpExit = true
+	cmd := p.NewCommand("cmd", "cmd description")
+	_ = cmd.Flag("f", "flag", nil)
+
+	err := p.Parse(testArgs)
+	if err != ErrHelpRequested {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), ErrHelpRequested, err)
+	}
+}
+
+func TestErrorPositions1(t *testing.T) {
+	testArgs := []string{"progname", "cmd", "-s", "value", "--count", "x"}
+
+	p := NewParser("", "description")
+	p.ErrorPositions = true
+	cmd := p.NewCommand("cmd", "")
+	_ = cmd.String("s", "string", nil)
+	_ = cmd.Int("c", "count", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-c|--count] must be an integer, got \"x\" at argument position 4"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	// Root argument used after sub-command still reports its place on command line
+	p = NewParser("", "description")
+	p.ErrorPositions = true
+	_ = p.Int("c", "count", nil)
+	_ = p.NewCommand("sub", "")
+
+	err = p.Parse([]string{"progname", "sub", "--count", "x"})
+	errStr = "[-c|--count] must be an integer, got \"x\" at argument position 2"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	p = NewParser("", "description")
+	_ = p.Int("c", "count", nil)
+
+	err = p.Parse([]string{"progname", "--count", "x"})
+	errStr = "[-c|--count] must be an integer, got \"x\""
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestParseErrorKind1(t *testing.T) {
+	newParser := func() *Parser {
+		p := NewParser("progname", "")
+		_ = p.Int("i", "int", nil)
+		_ = p.String("s", "string", &Options{Required: true})
+		_ = p.Flag("f", "flag", nil)
+		p.NewMutexGroup(p.Flag("a", "all", nil), p.Flag("n", "none", nil))
+		return p
+	}
+
+	testCases := []struct {
+		args []string
+		kind ErrorKind
+		code int
+		msg  string
+	}{
+		{[]string{"-s", "x", "-i", "one"}, KindBadValue, 2, `[-i|--int] must be an integer, got "one"`},
+		{[]string{"-i", "1"}, KindMissingRequired, 3, "[-s|--string] is required"},
+		{[]string{"-s", "x", "--flgg"}, KindUnknownArgument, 4, "unknown argument [--flgg], did you mean [--flag]?"},
+		{[]string{"-s", "x", "-f", "-f"}, KindConflict, 5, "[-f|--flag] can only be present once"},
+		{[]string{"-s", "x", "-a", "-n"}, KindConflict, 5, "only one of [--all --none] may be specified"},
+	}
+
+	for _, tc := range testCases {
+		err := newParser().Parse(append([]string{"progname"}, tc.args...))
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Test %s %v expected ParseError, got [%+v]", t.Name(), tc.args, err)
+			continue
+		}
+		if perr.Kind != tc.kind || perr.Code != tc.code || perr.Error() != tc.msg {
+			t.Errorf("Test %s %v expected [%s] error [%s] with code %d, got [%s] error [%s] with code %d",
+				t.Name(), tc.args, tc.kind, tc.msg, tc.code, perr.Kind, perr.Error(), perr.Code)
+		}
+	}
+}
+
+func TestParseErrorKind2(t *testing.T) {
+	p := NewParser("progname", "")
+	p.ErrorPositions = true
+	run := p.NewCommand("run", "")
+	_ = run.Int("i", "int", nil)
+
+	err := p.Parse([]string{"progname"})
+	sub, ok := err.(subCommandError)
+	if !ok {
+		t.Errorf("Test %s expected subCommandError, got [%+v]", t.Name(), err)
+		return
+	}
+	if perr, ok := sub.Unwrap().(*ParseError); !ok || perr.Kind != KindMissingRequired {
+		t.Errorf("Test %s expected ParseError of [%s] kind, got [%+v]", t.Name(), KindMissingRequired, sub.Unwrap())
+	}
+
+	err = p.Parse([]string{"progname", "run", "-i", "x"})
+	errStr := `[-i|--int] must be an integer, got "x" at argument position 2`
+	if perr, ok := err.(*ParseError); !ok || perr.Kind != KindBadValue || perr.Error() != errStr {
+		t.Errorf("Test %s expected ParseError [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestCollectErrors1(t *testing.T) {
+	testArgs := []string{"progname", "--count", "x", "--ratio", "y", "-s", "value", "--unknown"}
+
+	p := NewParser("", "description")
+	p.CollectErrors = true
+	_ = p.Int("c", "count", &Options{Required: true})
+	_ = p.Float("r", "ratio", nil)
+	s := p.String("s", "string", nil)
+	_ = p.String("n", "name", &Options{Required: true})
+
+	err := p.Parse(testArgs)
+	errStr := strings.Join([]string{
+		"[-c|--count] must be an integer, got \"x\"",
+		"[-r|--ratio] bad floating point value [y]",
+		"[-n|--name] is required",
+		"too many arguments",
+	}, "\n")
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	if *s != "value" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "value", *s)
+	}
+}
+
+func TestHelpRename1(t *testing.T) {
+	p := NewParser("progname", "description")
+	p.HelpShort = "?"
+	p.HelpLong = "usage"
+	p.DisableHelpExit = true
+	host := p.String("h", "host", nil)
+
+	err := p.Parse([]string{"progname", "-h", "localhost"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *host != "localhost" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "localhost", *host)
+	}
+
+	want := "usage: progname [-?|--usage] [-h|--host \"<value>\"]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+
+	for _, testArgs := range [][]string{{"progname", "-?"}, {"progname", "--usage"}} {
+		p := NewParser("progname", "description")
+		p.HelpShort = "?"
+		p.HelpLong = "usage"
+		p.DisableHelpExit = true
+		_ = p.String("h", "host", nil)
+
+		err := p.Parse(testArgs)
+		if err != ErrHelpRequested {
+			t.Errorf("Test %s expected [%s] for %s, got [%+v]", t.Name(), ErrHelpRequested, testArgs[1], err)
+		}
+	}
+}
+
+func TestHelpDisable1(t *testing.T) {
+	testArgs := []string{"progname", "cmd", "-h", "localhost"}
+
+	p := NewParser("progname", "description")
+	p.DisableHelp = true
+	cmd := p.NewCommand("cmd", "")
+	host := cmd.String("h", "host", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *host != "localhost" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "localhost", *host)
+	}
+
+	p = NewParser("progname", "description")
+	p.DisableHelp = true
+	cmd = p.NewCommand("cmd", "")
+	_ = cmd.String("h", "host", nil)
+
+	err = p.Parse([]string{"progname", "cmd", "--help"})
+	errStr := "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	want := "usage: progname cmd [-h|--host \"<value>\"]"
+	if usage := cmd.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestHelpExitFunc1(t *testing.T) {
+	testArgs := []string{"progname", "--help", "-s", "value"}
+
+	// Usage is printed to stdout, keep it out of test output
+	stdout := os.Stdout
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	defer func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	}()
+
+	p := NewParser("", "description")
+	code := -1
+	p.ExitFunc = func(c int) { code = c }
+	s := p.String("s", "string", nil)
+
+	err := p.Parse(testArgs)
+	if err != ErrHelpRequested {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), ErrHelpRequested, err)
+	}
+
+	if code != 0 {
+		t.Errorf("Test %s failed. Want exit code: [0], got: [%d]", t.Name(), code)
+	}
+
+	if *s != "" {
+		t.Errorf("Test %s failed. Parsing must stop on help, got: [%s]", t.Name(), *s)
+	}
+}
+
+func TestDurationSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--timeout", "1h30m"}
+
+	p := NewParser("", "description")
+	d1 := p.Duration("t", "timeout", nil)
+	d2 := p.Duration("", "interval", &Options{Default: 5 * time.Second})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *d1 != 90*time.Minute {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), 90*time.Minute, *d1)
+	}
+
+	if *d2 != 5*time.Second {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), 5*time.Second, *d2)
+	}
+}
+
+func TestDurationFail1(t *testing.T) {
+	testArgs := []string{"progname", "--timeout", "30"}
+
+	p := NewParser("", "description")
+	d1 := p.Duration("t", "timeout", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-t|--timeout] must be a duration such as 300ms or 1.5h"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		return
+	}
+
+	if *d1 != 0 {
+		t.Errorf("Test %s failed. Want: [0], got: [%s]", t.Name(), *d1)
+	}
+}
+
+func TestTimeSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--start", "2023-01-02T15:04:05Z", "--day", "2023-03-04"}
+
+	p := NewParser("progname", "description")
+	start := p.Time("s", "start", "", nil)
+	day := p.Time("d", "day", "2006-01-02", nil)
+	def := p.Time("", "end", "", &Options{Default: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if want := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, *start)
+	}
+
+	if want := time.Date(2023, 3, 4, 0, 0, 0, 0, time.UTC); !day.Equal(want) {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, *day)
+	}
+
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !def.Equal(want) {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, *def)
+	}
+
+	want := "usage: progname [-h|--help] [-s|--start <time>]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestTimeFail1(t *testing.T) {
+	testArgs := []string{"progname", "--day", "04/03/2023"}
+
+	p := NewParser("", "description")
+	_ = p.Time("d", "day", "2006-01-02", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-d|--day] must be a time in layout \"2006-01-02\", got \"04/03/2023\""
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestEnvVarSimple1(t *testing.T) {
+	os.Setenv("ARGPARSE_TEST_PORT", "8080")
+	os.Setenv("ARGPARSE_TEST_HOST", "envhost")
+	defer os.Unsetenv("ARGPARSE_TEST_PORT")
+	defer os.Unsetenv("ARGPARSE_TEST_HOST")
+
+	testArgs := []string{"progname", "--host", "clihost"}
+
+	p := NewParser("", "description")
+	i1 := p.Int("p", "port", &Options{Required: true, EnvVar: "ARGPARSE_TEST_PORT", Default: 80})
+	s1 := p.String("", "host", &Options{EnvVar: "ARGPARSE_TEST_HOST"})
+	s2 := p.String("", "user", &Options{EnvVar: "ARGPARSE_TEST_USER", Default: "nobody"})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *i1 != 8080 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 8080, *i1)
+	}
+
+	if *s1 != "clihost" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "clihost", *s1)
+	}
+
+	if *s2 != "nobody" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "nobody", *s2)
+	}
+}
+
+func TestEnvVarSelectorFail1(t *testing.T) {
+	os.Setenv("ARGPARSE_TEST_LEVEL", "TRACE")
+	defer os.Unsetenv("ARGPARSE_TEST_LEVEL")
+
+	testArgs := []string{"progname"}
+
+	p := NewParser("", "description")
+	_ = p.Selector("l", "level", []string{"INFO", "DEBUG"}, &Options{EnvVar: "ARGPARSE_TEST_LEVEL"})
+
+	err := p.Parse(testArgs)
+	errStr := "bad value for [-l|--level]. Allowed values are [INFO DEBUG]"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestSuggestTypo1(t *testing.T) {
+	testArgs := []string{"progname", "cmd", "--verbsoe"}
+
+	p := NewParser("", "description")
+	cmd := p.NewCommand("cmd", "cmd description")
+	_ = cmd.Flag("v", "verbose", nil)
+	_ = p.Flag("q", "quiet", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "unknown argument [--verbsoe], did you mean [--verbose]?"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestSuggestTypoAmbiguous1(t *testing.T) {
+	testArgs := []string{"progname", "--lavel"}
+
+	p := NewParser("", "description")
+	_ = p.String("", "label", nil)
+	_ = p.String("", "level", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestSuggestTypoTooFar1(t *testing.T) {
+	testArgs := []string{"progname", "--vrbsoe"}
+
+	p := NewParser("", "description")
+	_ = p.Flag("v", "verbose", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestAbbreviations1(t *testing.T) {
+	testArgs := []string{"progname", "cmd", "--verb", "--out=file.txt", "--lev", "3", "--ver", "1.0"}
+
+	p := NewParser("", "description")
+	p.AllowAbbreviations = true
+	version := p.String("", "versionname", nil)
+	cmd := p.NewCommand("cmd", "")
+	verbose := cmd.Flag("", "verbose", nil)
+	output := cmd.String("", "output", nil)
+	level := cmd.Int("", "level", nil)
+	ver := cmd.String("", "ver", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !*verbose {
+		t.Errorf("Test %s failed. [--verbose] was not set by [--verb]", t.Name())
+	}
+	if *output != "file.txt" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "file.txt", *output)
+	}
+	if *level != 3 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 3, *level)
+	}
+	if *ver != "1.0" || *version != "" {
+		t.Errorf("Test %s failed. Exact match must win, got: [%s] and [%s]", t.Name(), *ver, *version)
+	}
+}
+
+func TestAbbreviationsAmbiguous1(t *testing.T) {
+	testArgs := []string{"progname", "--ver"}
+
+	p := NewParser("", "description")
+	p.AllowAbbreviations = true
+	_ = p.Flag("", "version", nil)
+	_ = p.Flag("", "verbose", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "ambiguous flag --ver: could be --verbose, --version"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	p = NewParser("", "description")
+	_ = p.Flag("", "verbose", nil)
+
+	err = p.Parse([]string{"progname", "--verb"})
+	errStr = "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s] without abbreviations, got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestListOccurrences1(t *testing.T) {
+	testArgs := []string{"progname", "--header", "a", "--header", "b", "--header", "c", "--tag", "x,y"}
+
+	p := NewParser("", "description")
+	headers := p.List("", "header", &Options{MinOccurrences: 1, MaxOccurrences: 3})
+	tags := p.List("", "tag", &Options{Separator: ",", MaxOccurrences: 1})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if len(*headers) != 3 || len(*tags) != 2 {
+		t.Errorf("Test %s failed. Got: [%v] and [%v]", t.Name(), *headers, *tags)
+	}
+}
+
+func TestListOccurrencesFail1(t *testing.T) {
+	testArgsList := map[string][]string{
+		"[--header] may be specified at most 3 times":   {"progname", "--header", "a", "--header", "b", "--header", "c", "--header", "d"},
+		"[--header] must be specified at least 2 times": {"progname", "--header", "a"},
+	}
+
+	for errStr, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		_ = p.List("", "header", &Options{MinOccurrences: 2, MaxOccurrences: 3})
+
+		err := p.Parse(testArgs)
+		if err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		}
+	}
+}
+
+func TestIntListSimple1(t *testing.T) {
+	testArgs := []string{"progname", "-i", "1", "--id", "2", "-i", "3"}
+
+	p := NewParser("", "description")
+	l1 := p.IntList("i", "id", nil)
+	l2 := p.IntList("", "other", &Options{Default: []int{7}})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !reflect.DeepEqual(*l1, []int{1, 2, 3}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), []int{1, 2, 3}, *l1)
+	}
+
+	if !reflect.DeepEqual(*l2, []int{7}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), []int{7}, *l2)
+	}
+}
+
+func TestIntListFail1(t *testing.T) {
+	testArgs := []string{"progname", "-i", "1", "-i", "two"}
+
+	p := NewParser("", "description")
+	_ = p.IntList("i", "id", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[-i|--id] must be an integer, got \"two\""
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+type hostPort struct {
+	host string
+	port int
+}
+
+func parseHostPort(value string) (interface{}, error) {
+	i := strings.LastIndex(value, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("missing port")
+	}
+	port, err := strconv.Atoi(value[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("bad port")
+	}
+	return hostPort{host: value[:i], port: port}, nil
+}
+
+func TestListFuncSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--peer", "a:1", "-p", "b:2", "--peer=c:3"}
+
+	p := NewParser("progname", "")
+	peers := p.ListFunc("p", "peer", parseHostPort, nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	want := []interface{}{hostPort{"a", 1}, hostPort{"b", 2}, hostPort{"c", 3}}
+	if !reflect.DeepEqual(*peers, want) {
+		t.Errorf("Test %s failed. Want: %v, got: %v", t.Name(), want, *peers)
+	}
+
+	wantUsage := "usage: progname [-h|--help] [-p|--peer <value> [-p|--peer <value> ...]]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, wantUsage) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), wantUsage, usage)
+	}
+
+	p.Reset()
+	err = p.Parse([]string{"progname", "--peer", "a:1", "--peer", "b"})
+	if err == nil || err.Error() != "[-p|--peer] bad value \"b\": missing port" {
+		t.Errorf("Test %s expected callback error, got %v", t.Name(), err)
+	}
+}
+
+func TestFloatListSimple1(t *testing.T) {
+	min := 0.0
+	max := 1.0
+	testArgs := []string{"progname", "--weight", "0.1", "-w", "0.25,0.5", "--weight", "1"}
+
+	p := NewParser("", "description")
+	l1 := p.FloatList("w", "weight", &Options{Min: &min, Max: &max, Separator: ","})
+	l2 := p.FloatList("", "other", &Options{Default: []float64{1.5}})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if want := []float64{0.1, 0.25, 0.5, 1}; !reflect.DeepEqual(*l1, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *l1)
+	}
+
+	if want := []float64{1.5}; !reflect.DeepEqual(*l2, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *l2)
+	}
+}
+
+func TestFloatListRangeFail1(t *testing.T) {
+	min := 0.0
+	max := 1.0
+	testArgsList := map[string][]string{
+		"[--weight] value 2 exceeds max 1":        {"progname", "--weight", "0.1", "--weight", "2.0"},
+		"[--weight] value -0.5 is below min 0":    {"progname", "--weight", "0.1,-0.5"},
+		"[--weight] bad floating point value [x]": {"progname", "--weight", "x"},
+	}
+
+	for errStr, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		_ = p.FloatList("", "weight", &Options{Min: &min, Max: &max, Separator: ","})
+
+		err := p.Parse(testArgs)
+		if err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		}
+	}
+}
+
+func TestListSeparator1(t *testing.T) {
+	testArgs := []string{"progname", "--tag", "a,b", "-t", "c", "--skip", "x,,y,", "--keep", "x,,y"}
+
+	p := NewParser("", "description")
+	tags := p.List("t", "tag", &Options{Separator: ","})
+	skip := p.List("", "skip", &Options{Separator: ",", SkipEmpty: true})
+	keep := p.List("", "keep", &Options{Separator: ","})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !reflect.DeepEqual(*tags, []string{"a", "b", "c"}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), []string{"a", "b", "c"}, *tags)
+	}
+	if !reflect.DeepEqual(*skip, []string{"x", "y"}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), []string{"x", "y"}, *skip)
+	}
+	if !reflect.DeepEqual(*keep, []string{"x", "", "y"}) {
+		t.Errorf("Test %s failed. Want: [%q], got: [%q]", t.Name(), []string{"x", "", "y"}, *keep)
+	}
+}
+
+func TestIntListSeparator1(t *testing.T) {
+	testArgs := []string{"progname", "--ids", "1,2,3", "--ids", "4"}
+
+	p := NewParser("", "description")
+	ids := p.IntList("", "ids", &Options{Separator: ","})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !reflect.DeepEqual(*ids, []int{1, 2, 3, 4}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), []int{1, 2, 3, 4}, *ids)
+	}
+
+	p = NewParser("", "description")
+	_ = p.IntList("", "ids", &Options{Separator: ","})
+
+	err = p.Parse([]string{"progname", "--ids", "1,,3"})
+	errStr := "[--ids] must be an integer, got \"\""
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestStringMapSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--label", "env=prod", "-l", "team=infra", "--label", "env=dev", "-l", "expr=a=b", "-l", "empty="}
+
+	p := NewParser("", "description")
+	labels := p.StringMap("l", "label", nil)
+	tags := p.StringMap("t", "tag", &Options{Default: map[string]string{"a": "b"}})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	want := map[string]string{"env": "dev", "team": "infra", "expr": "a=b", "empty": ""}
+	if !reflect.DeepEqual(*labels, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *labels)
+	}
+
+	if !reflect.DeepEqual(*tags, map[string]string{"a": "b"}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), map[string]string{"a": "b"}, *tags)
+	}
+
+	usage := "[-l|--label <key=value>]"
+	if u := p.Usage(nil); !strings.Contains(u, usage) {
+		t.Errorf("Test %s failed. Usage does not contain [%s]: [%s]", t.Name(), usage, u)
+	}
+}
+
+func TestStringMapFail1(t *testing.T) {
+	testArgsList := map[string][]string{
+		"[-l|--label] must be in key=value form":              {"progname", "--label", "env"},
+		"[-l|--label] must be in key=value form ":             {"progname", "--label", "=prod"},
+		`[-l|--label] key "env" is provided more than once`:   {"progname", "-l", "env=prod", "-l", "env=dev"},
+		`[-l|--label] key "team" is provided more than once `: {"progname", "-l", "team=a,team=b"},
+	}
+
+	for errStr, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		_ = p.StringMap("l", "label", &Options{UniqueKeys: true, Separator: ","})
+
+		err := p.Parse(testArgs)
+		if errStr = strings.TrimSpace(errStr); err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		}
+	}
+}
+
+func TestResponseFiles1(t *testing.T) {
+	// Test file locations
+	fpath := "./test.tmp"
+	npath := "./nested.tmp"
+	err := ioutil.WriteFile(fpath, []byte("--name 'John Smith'\n  -t a\\ b -t \"x  y\" @"+npath+"\n"), 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(fpath)
+	err = ioutil.WriteFile(npath, []byte("-t \"say \\\"hi\\\"\" -v"), 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(npath)
+
+	testArgs := []string{"progname", "@" + fpath, "-t", "last", "--", "@" + fpath}
+
+	p := NewParser("", "description")
+	p.EnableResponseFiles('@')
+	name := p.String("n", "name", nil)
+	tags := p.List("t", "tag", nil)
+	verbose := p.Flag("v", "verbose", nil)
+
+	err = p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *name != "John Smith" || !*verbose {
+		t.Errorf("Test %s failed. Got: name [%s], verbose [%t]", t.Name(), *name, *verbose)
+	}
+
+	want := []string{"a b", "x  y", `say "hi"`, "last"}
+	if !reflect.DeepEqual(*tags, want) {
+		t.Errorf("Test %s failed. Want: [%q], got: [%q]", t.Name(), want, *tags)
+	}
+
+	if !reflect.DeepEqual(p.Remaining(), []string{"@" + fpath}) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), []string{"@" + fpath}, p.Remaining())
+	}
+}
+
+func TestResponseFilesFail1(t *testing.T) {
+	// Test file location
+	fpath := "./test.tmp"
+	err := ioutil.WriteFile(fpath, []byte("@"+fpath), 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(fpath)
+
+	p := NewParser("", "description")
+	p.EnableResponseFiles('@')
+
+	err = p.Parse([]string{"progname", "@" + fpath})
+	errStr := "response file [./test.tmp] is nested more than 10 levels deep"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	err = ioutil.WriteFile(fpath, []byte("--name 'John"), 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = p.Parse([]string{"progname", "@" + fpath})
+	errStr = "response file [./test.tmp] has unterminated ' quote"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestParseArgsSimple1(t *testing.T) {
+	testArgs := []string{"--name", "value"}
+
+	p := NewParser("progname", "description")
+	s1 := p.String("n", "name", nil)
+
+	err := p.ParseArgs(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *s1 != "value" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "value", *s1)
+	}
+
+	if testArgs[0] != "--name" || testArgs[1] != "value" {
+		t.Errorf("Test %s failed. Original arguments were modified: %v", t.Name(), testArgs)
+	}
+
+	// Parser without name still takes first argument as an argument
+	p = NewParser("", "description")
+	s1 = p.String("n", "name", nil)
+
+	err = p.ParseArgs(testArgs)
+	if err != nil || *s1 != "value" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s] with error [%v]", t.Name(), "value", *s1, err)
+	}
+}
+
+func TestParseEmpty1(t *testing.T) {
+	for _, testArgs := range [][]string{{}, nil} {
+		p := NewParser("", "description")
+		_ = p.String("s", "string", &Options{ConsumeRaw: true})
+
+		err := p.Parse(testArgs)
+		if err != nil {
+			t.Errorf("Test %s %#v failed with error: %s", t.Name(), testArgs, err.Error())
+		}
+	}
+}
+
+func TestRangeInt1(t *testing.T) {
+	min, max := 1.0, 100.0
+
+	p := NewParser("", "description")
+	i1 := p.Int("c", "count", &Options{Min: &min, Max: &max})
+
+	err := p.Parse([]string{"progname", "--count", "100"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *i1 != 100 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), 100, *i1)
+	}
+}
+
+func TestRangeIntFail1(t *testing.T) {
+	min, max := 1.0, 100.0
+
+	p := NewParser("", "description")
+	_ = p.Int("c", "count", &Options{Min: &min, Max: &max})
+
+	err := p.Parse([]string{"progname", "--count", "0"})
+	errStr := "[-c|--count] must be >= 1"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestRangeFloatFail1(t *testing.T) {
+	max := 0.5
+
+	p := NewParser("", "description")
+	_ = p.Float("t", "threshold", &Options{Max: &max})
+
+	err := p.Parse([]string{"progname", "--threshold", "0.75"})
+	errStr := "[-t|--threshold] must be <= 0.5"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestRangeIntFail2(t *testing.T) {
+	// 2^53 + 1 turns into 2^53 once converted to float64, so it has to be compared as integer
+	max := float64(1 << 53)
+
+	p := NewParser("", "description")
+	i1 := p.Int64("", "id", &Options{Max: &max})
+	_ = p.IntList("", "ids", &Options{Max: &max})
+
+	err := p.Parse([]string{"progname", "--id", "9007199254740992"})
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if *i1 != 1<<53 {
+		t.Errorf("Test %s failed. Want: [%d], got: [%d]", t.Name(), int64(1<<53), *i1)
+	}
+
+	errStr := "[--id] must be <= 9.007199254740992e+15"
+	p.Reset()
+	err = p.Parse([]string{"progname", "--id", "9007199254740993"})
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	errStr = "[--ids] value 9007199254740993 exceeds max 9.007199254740992e+15"
+	p.Reset()
+	err = p.Parse([]string{"progname", "--ids", "9007199254740993"})
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestRangeMinOverMaxPanic1(t *testing.T) {
+	min, max := 10.0, 1.0
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Test %s failed. Min greater than Max was accepted", t.Name())
+		}
+	}()
+
+	p := NewParser("", "description")
+	_ = p.Int("c", "count", &Options{Min: &min, Max: &max})
+}
+
+func TestPositionalSimple1(t *testing.T) {
+	testArgs := []string{"progname", "input.txt", "-v", "output.txt"}
+
+	p := NewParser("", "description")
+	v := p.Flag("v", "verbose", nil)
+	in := p.Positional("input", &Options{Required: true})
+	out := p.Positional("output", nil)
+	extra := p.Positional("extra", &Options{Default: "none"})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *v != true {
+		t.Errorf("Test %s failed with verbose being false", t.Name())
+	}
+
+	if *in != "input.txt" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "input.txt", *in)
+	}
+
+	if *out != "output.txt" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "output.txt", *out)
+	}
+
+	if *extra != "none" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "none", *extra)
+	}
+}
+
+func TestPositionalInterspersed1(t *testing.T) {
+	testArgsList := [][]string{
+		{"progname", "--flag", "val", "-v", "a", "b"},
+		{"progname", "a", "--flag", "val", "b", "-v"},
+		{"progname", "a", "-v", "b", "--flag=val"},
+		{"progname", "run", "a", "-f", "val", "-v", "b"},
+	}
+
+	for _, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		var f *string
+		var v *bool
+		var first, second *string
+		if testArgs[1] == "run" {
+			cmd := p.NewCommand("run", "")
+			v = p.Flag("v", "verbose", nil)
+			f = cmd.String("f", "flag", nil)
+			first = cmd.Positional("first", nil)
+			second = cmd.Positional("second", nil)
+		} else {
+			f = p.String("f", "flag", nil)
+			v = p.Flag("v", "verbose", nil)
+			first = p.Positional("first", nil)
+			second = p.Positional("second", nil)
+		}
+
+		err := p.Parse(testArgs)
+		if err != nil {
+			t.Errorf("Test %s failed with error: %s for %v", t.Name(), err.Error(), testArgs)
+			continue
+		}
+		if *f != "val" || !*v || *first != "a" || *second != "b" {
+			t.Errorf("Test %s got %q, %t, %q and %q for %v", t.Name(), *f, *v, *first, *second, testArgs)
+		}
+	}
+}
+
+func TestPositionalListInterspersed1(t *testing.T) {
+	p := NewParser("", "description")
+	f := p.String("f", "flag", nil)
+	src := p.PositionalList("src", nil)
+	dst := p.Positional("dst", nil)
+
+	err := p.Parse([]string{"progname", "a", "--flag", "x", "b", "--", "-c", "d"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	if *f != "x" || !reflect.DeepEqual(*src, []string{"a", "b", "-c"}) || *dst != "d" {
+		t.Errorf("Test %s got %q, %q and %q", t.Name(), *f, *src, *dst)
+	}
+}
+
+func TestPositionalRequiredFail1(t *testing.T) {
+	testArgs := []string{"progname", "-v"}
+
+	p := NewParser("", "description")
+	_ = p.Flag("v", "verbose", nil)
+	_ = p.Positional("input", &Options{Required: true})
+
+	err := p.Parse(testArgs)
+	errStr := "[input] is required"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestPositionalTerminator1(t *testing.T) {
+	testArgs := []string{"progname", "cmd", "--", "-v", "--weird"}
+
+	p := NewParser("", "description")
+	cmd := p.NewCommand("cmd", "cmd description")
+	v := cmd.Flag("v", "verbose", nil)
+	first := cmd.Positional("first", nil)
+	second := cmd.Positional("second", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *v != false {
+		t.Errorf("Test %s failed with verbose being true", t.Name())
+	}
+
+	if *first != "-v" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "-v", *first)
+	}
+
+	if *second != "--weird" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "--weird", *second)
+	}
+}
+
+func TestRemaining1(t *testing.T) {
+	testArgs := []string{"progname", "-v", "input.txt", "--", "output.txt", "--not-a-flag", "", "-v"}
+
+	p := NewParser("", "description")
+	v := p.Flag("v", "verbose", nil)
+	in := p.Positional("input", nil)
+	out := p.Positional("output", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if *v != true {
+		t.Errorf("Test %s failed with verbose being false", t.Name())
+	}
+
+	if *in != "input.txt" || *out != "output.txt" {
+		t.Errorf("Test %s failed. Want: [input.txt output.txt], got: [%s %s]", t.Name(), *in, *out)
+	}
+
+	if !reflect.DeepEqual(p.Remaining(), []string{"--not-a-flag", "", "-v"}) {
+		t.Errorf("Test %s failed. Want: [%q], got: [%q]", t.Name(), []string{"--not-a-flag", "", "-v"}, p.Remaining())
+	}
+}
+
+func TestPassThroughUnknown1(t *testing.T) {
+	testArgs := []string{"progname", "--foo=bar", "-v", "input.txt", "-x", "--", "--baz"}
+
+	p := NewParser("", "description")
+	p.PassThroughUnknown = true
+	v := p.Flag("v", "verbose", nil)
+	in := p.Positional("input", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !*v || *in != "input.txt" {
+		t.Errorf("Test %s failed. Got: verbose [%t], input [%s]", t.Name(), *v, *in)
+	}
+
+	if want := []string{"--foo=bar", "-x"}; !reflect.DeepEqual(p.Unknown(), want) {
+		t.Errorf("Test %s failed. Want: [%q], got: [%q]", t.Name(), want, p.Unknown())
+	}
+
+	if want := []string{"--baz"}; !reflect.DeepEqual(p.Remaining(), want) {
+		t.Errorf("Test %s failed. Want: [%q], got: [%q]", t.Name(), want, p.Remaining())
+	}
+
+	p = NewParser("", "description")
+	p.PassThroughUnknown = true
+
+	err = p.Parse([]string{"progname", "--foo", "bar"})
+	errStr := "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestPositionalTooManyFail1(t *testing.T) {
+	testArgs := []string{"progname", "a", "b"}
+
+	p := NewParser("", "description")
+	_ = p.Positional("input", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestPositionalList1(t *testing.T) {
+	testArgs := []string{"progname", "a.txt", "-v", "b.txt", "c.txt", "--", "-d.txt", "dst"}
+
+	p := NewParser("progname", "description")
+	v := p.Flag("v", "verbose", nil)
+	mode := p.Positional("mode", nil)
+	sources := p.PositionalList("src", &Options{Required: true})
+	dst := p.Positional("dst", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+
+	if !*v || *mode != "a.txt" || *dst != "dst" {
+		t.Errorf("Test %s failed. Got: verbose [%t], mode [%s], dst [%s]", t.Name(), *v, *mode, *dst)
+	}
+
+	if want := []string{"b.txt", "c.txt", "-d.txt"}; !reflect.DeepEqual(*sources, want) {
+		t.Errorf("Test %s failed. Want: [%q], got: [%q]", t.Name(), want, *sources)
+	}
+
+	if len(p.Remaining()) != 0 {
+		t.Errorf("Test %s failed. Want no remaining arguments, got: [%q]", t.Name(), p.Remaining())
+	}
+
+	want := "usage: progname [-h|--help] [-v|--verbose] [mode] src... [dst]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestPositionalListRequiredFail1(t *testing.T) {
+	testArgs := []string{"progname", "dst"}
+
+	p := NewParser("", "description")
+	sources := p.PositionalList("src", &Options{Required: true})
+	dst := p.Positional("dst", nil)
+
+	err := p.Parse(testArgs)
+	errStr := "[src] is required"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	if len(*sources) != 0 || *dst != "dst" {
+		t.Errorf("Test %s failed. Got: src [%q], dst [%s]", t.Name(), *sources, *dst)
+	}
+}
+
+var positionalUsage = `usage: prog [-h|--help] [-v|--verbose] input [output]
+
+            program description
+
+Positional arguments:
+
+  input   Input file
+  output  Output file
+
+Arguments:
+
+  -h  --help     Print help information
+  -v  --verbose  Verbose output
+
+`
+
+func TestPositionalUsage1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	_ = p.Positional("input", &Options{Required: true, Help: "Input file"})
+	_ = p.Positional("output", &Options{Help: "Output file"})
+	_ = p.Flag("v", "verbose", &Options{Help: "Verbose output"})
+
+	if usage := p.Usage(nil); usage != positionalUsage {
+		t.Errorf("%s", usage)
+	}
+}
+
+var epilogUsage = `usage: prog [-h|--help] [-d|--date "<value>"]
+
+            program description
+
+Arguments:
+
+  -h  --help  Print help information
+  -d  --date  Start date
+              e.g. --date 2023-01-02
+              e.g. --date today
+
+  See project page for more details.
+  Report bugs to the issue tracker.
+
+`
+
+func TestUsageEpilog1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	p.Epilog = "See project page for more details.\nReport bugs to the issue tracker."
+	_ = p.String("d", "date", &Options{Help: "Start date", Examples: []string{"--date 2023-01-02", "--date today"}})
+
+	if usage := p.Usage(nil); usage != epilogUsage {
+		t.Errorf("%s", usage)
+	}
+}
+
+var groupUsage = `usage: prog [-h|--help] [-b|--bind "<value>"] [-o|--output "<value>"] [-p|--port <integer>]
+            [-v|--verbose]
+
+            program description
+
+Arguments:
+
+  -h  --help     Print help information
+  -v  --verbose  Verbose output
+
+Networking:
+
+  -b  --bind     Address to listen on
+  -p  --port     Port to listen on
+
+Output:
+
+  -o  --output   Output file
+
+`
+
+func TestUsageCommandSummary1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	remote := p.NewCommand("remote", "Manage remotes\nRemotes are listed, added and removed by sub-commands.")
+
+	want := "Commands:\n\n  remote  Manage remotes\n\n"
+	if usage := p.Usage(nil); !strings.Contains(usage, want) {
+		t.Errorf("Test %s failed. Usage does not contain [%s]: [%s]", t.Name(), want, usage)
+	}
+
+	want = "Remotes are listed, added and removed by sub-commands."
+	if usage := remote.Usage(nil); !strings.Contains(usage, want) {
+		t.Errorf("Test %s failed. Usage does not contain [%s]: [%s]", t.Name(), want, usage)
+	}
+}
+
+func TestUsageGroup1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	p.SetWidth(100)
+	_ = p.String("b", "bind", &Options{Group: "Networking", Help: "Address to listen on"})
+	_ = p.String("o", "output", &Options{Group: "Output", Help: "Output file"})
+	_ = p.Int("p", "port", &Options{Group: "Networking", Help: "Port to listen on"})
+	_ = p.Flag("v", "verbose", &Options{Help: "Verbose output"})
+
+	if usage := p.Usage(nil); usage != groupUsage {
+		t.Errorf("%s", usage)
+	}
+}
+
+var narrowUsage = `usage: prog [-h|--help]
+            [-s|--string
+            "<value>"]
+
+            program
+            description
+
+Arguments:
+
+  -h  --help    Print help
+                information
+  -s  --string  A string with
+                long
+                description
+
+`
+
+func TestUsageSetWidth1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	p.SetWidth(30)
+	_ = p.String("s", "string", &Options{Help: "A string with long description"})
+
+	if usage := p.Usage(nil); usage != narrowUsage {
+		t.Errorf("%s", usage)
+	}
+}
+
+func TestUsageDefault1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	p.SetWidth(400)
+	_ = p.String("o", "output", &Options{Default: "out.txt"})
+	_ = p.Flag("c", "color", &Options{Default: true, Negatable: true})
+	_ = p.Duration("t", "timeout", &Options{Default: 90 * time.Second})
+	_ = p.List("", "tag", &Options{Default: []string{"a", "b"}})
+
+	expected := []string{
+		`[-o|--output "<value>"] (default: out.txt)`,
+		`[-c|--color|--no-color] (default: true)`,
+		`[-t|--timeout <duration>] (default: 1m30s)`,
+		`[--tag "<value>" [--tag "<value>" ...]] (default: a,b)`,
+	}
+	usage := p.Usage(nil)
+	for _, v := range expected {
+		if !strings.Contains(usage, v) {
+			t.Errorf("Test %s failed. Usage does not contain [%s]:\n%s", t.Name(), v, usage)
+		}
+	}
+}
+
+var pUsageString = `test string
+usage: prog [-h|--help]
+
+            program description
+
+Arguments:
+
+  -h  --help  Print help information
+
+`
+
+func TestUsageString(t *testing.T) {
+	p := NewParser("prog", "program description")
+
+	p.Parse(os.Args)
+
+	usage := p.Usage("test string")
+
+	if usage != pUsageString {
+		t.Errorf("%s", usage)
+	}
+}
+
+type s string
+
+func (s s) String() string {
+	return string(s)
+}
+
+var pUsageStringer = `stringer message
+usage: prog [-h|--help]
+
+            program description
+
+Arguments:
+
+  -h  --help  Print help information
+
+`
+
+func TestUsageStringer(t *testing.T) {
+	p := NewParser("prog", "program description")
+
+	p.Parse(os.Args)
+
+	var msg s = "stringer message"
+
+	usage := p.Usage(msg)
+
+	if usage != pUsageStringer {
+		t.Errorf("%s", usage)
+	}
+}
+
+func TestBashCompletion1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	_ = p.Flag("v", "verbose", nil)
+	_ = p.Selector("l", "level", []string{"info", "debug"}, nil)
+	remote := p.NewCommand("remote", "remote description")
+	_ = remote.File("f", "file", os.O_RDONLY, 0600, nil)
+	_ = remote.FileList("i", "input", os.O_RDONLY, 0600, nil)
+
+	script := p.BashCompletion("prog")
+
+	expected := []string{
+		"_prog_completion() {",
+		"'prog remote') cmd=\"$cmd ${COMP_WORDS[i]}\" ;;",
+		"'-l'|'--level') COMPREPLY=($(compgen -W 'info debug' -- \"$cur\")); return 0 ;;",
+		"'-f'|'--file') COMPREPLY=($(compgen -f -- \"$cur\")); return 0 ;;",
+		"'-i'|'--input') COMPREPLY=($(compgen -f -- \"$cur\")); return 0 ;;",
+		"COMPREPLY=($(compgen -W 'remote -h --help -v --verbose -l --level' -- \"$cur\"))",
+		"complete -F _prog_completion 'prog'",
+	}
+	for _, v := range expected {
+		if !strings.Contains(script, v) {
+			t.Errorf("Test %s failed. Script does not contain [%s]:\n%s", t.Name(), v, script)
+		}
+	}
+}
+
+func TestZshCompletion1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	_ = p.Flag("v", "verbose", &Options{Help: "Print more [details]"})
+	_ = p.Selector("l", "level", []string{"info", "debug"}, nil)
+	remote := p.NewCommand("remote", "remote description")
+	_ = remote.File("f", "file", os.O_RDONLY, 0600, nil)
+	_ = remote.FileList("i", "input", os.O_RDONLY, 0600, nil)
+
+	script := p.ZshCompletion("prog")
+
+	expected := []string{
+		"#compdef prog\n",
+		"_prog_completion() {",
+		"'prog remote') cmd=\"$cmd ${words[i]}\" ;;",
+		"commands=('remote:remote description')",
+		"'-v[Print more \\[details\\]]' '--verbose[Print more \\[details\\]]'",
+		"'-l[]:value:(info debug)'",
+		"'-f[]:file:_files'",
+		"'*-i[]:file:_files'",
+		"compdef _prog_completion 'prog'",
+	}
+	for _, v := range expected {
+		if !strings.Contains(script, v) {
+			t.Errorf("Test %s failed. Script does not contain [%s]:\n%s", t.Name(), v, script)
+		}
+	}
+}
+
+func TestFishCompletion1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	_ = p.Flag("v", "verbose", &Options{Help: "Print more details"})
+	_ = p.Selector("l", "level", []string{"info", "debug"}, nil)
+	remote := p.NewCommand("remote", "remote's description")
+	_ = remote.File("f", "file", os.O_RDONLY, 0600, nil)
+	_ = remote.FileList("i", "input", os.O_RDONLY, 0600, nil)
+
+	script := p.FishCompletion("prog")
+
+	expected := []string{
+		"function _prog_completion\n",
+		"case 'prog remote'\n",
+		"complete -c 'prog' -f\n",
+		"complete -c 'prog' -n 'test (_prog_completion) = \\'prog\\'' -a 'remote' -d 'remote\\'s description'\n",
+		"-s 'v' -l 'verbose' -d 'Print more details'\n",
+		"-s 'l' -l 'level' -x -a 'info debug' -d ''\n",
+		"complete -c 'prog' -n 'test (_prog_completion) = \\'prog remote\\'' -s 'f' -l 'file' -r -F -d ''\n",
+		"complete -c 'prog' -n 'test (_prog_completion) = \\'prog remote\\'' -s 'i' -l 'input' -r -F -d ''\n",
+	}
+	for _, v := range expected {
+		if !strings.Contains(script, v) {
+			t.Errorf("Test %s failed. Script does not contain [%s]:\n%s", t.Name(), v, script)
+		}
+	}
+}
+
+func TestHelpJSON1(t *testing.T) {
+	p := NewParser("progname", "Does things")
+	p.DisableHelp = true
+	p.Selector("m", "mode", []string{"fast", "slow"}, &Options{Default: "fast", Help: "Mode"})
+	p.String("", "secret", &Options{Hidden: true})
+	run := p.NewCommand("run", "Runs it")
+	a := run.Flag("a", "all", nil)
+	n := run.Flag("", "none", nil)
+	run.NewMutexGroup(a, n)
+	run.Positional("target", &Options{Required: true})
+	p.NewCommand("internal", DisableDescription)
+
+	want := `{
+  "schema": 1,
+  "name": "progname",
+  "description": "Does things",
+  "arguments": [
+    {
+      "short": "m",
+      "long": "mode",
+      "type": "selector",
+      "help": "Mode",
+      "required": false,
+      "positional": false,
+      "env": false,
+      "default": "fast",
+      "selector": [
+        "fast",
+        "slow"
+      ]
+    }
+  ],
+  "commands": [
+    {
+      "name": "run",
+      "description": "Runs it",
+      "arguments": [
+        {
+          "short": "a",
+          "long": "all",
+          "type": "flag",
+          "required": false,
+          "positional": false,
+          "env": false
+        },
+        {
+          "long": "none",
+          "type": "flag",
+          "required": false,
+          "positional": false,
+          "env": false
+        },
+        {
+          "long": "target",
+          "type": "string",
+          "required": true,
+          "positional": true,
+          "env": false
+        }
+      ],
+      "constraints": [
+        {
+          "kind": "at-most-one",
+          "arguments": [
+            "all",
+            "none"
+          ]
+        }
+      ]
+    }
+  ]
+}`
+	if got := string(p.HelpJSON()); got != want {
+		t.Errorf("Test %s failed, got:\n%s", t.Name(), got)
+	}
+}
+
+func TestManPage1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	p.Epilog = ".Report bugs to the issue tracker"
+	_ = p.String("s", "string", &Options{Help: "String to print", Examples: []string{"--string hello"}})
+	_ = p.Flag("", "internal", &Options{Hidden: true})
+	_ = p.Positional("input", &Options{Help: "Input file"})
+	remote := p.NewCommand("remote", "remote description")
+	_ = remote.Int("n", "count", &Options{Default: 3, Help: "Number of retries"})
+
+	page := p.ManPage(1)
+
+	expected := []string{
+		".TH PROG 1\n.SH NAME\nprog \\- program description\n",
+		".SH SYNOPSIS\n.B prog\n<command> [\\-h|\\-\\-help] [\\-s|\\-\\-string \"<value>\"] [input]\n",
+		".TP\n\\fB\\-s\\fR, \\fB\\-\\-string\\fR \\fI\"<value>\"\\fR\nString to print\n.RS\n.nf\n\\-\\-string hello\n.fi\n.RE\n",
+		".TP\n\\fIinput\\fR\nInput file\n",
+		".SH SUBCOMMANDS\n.TP\n\\fBremote\\fR\nremote description\n.RS\n",
+		"\\fB\\-n\\fR, \\fB\\-\\-count\\fR \\fI<integer>\\fR\nNumber of retries. Default: 3\n.RE\n",
+		".SH NOTES\n\\&.Report bugs to the issue tracker\n",
+	}
+	for _, v := range expected {
+		if !strings.Contains(page, v) {
+			t.Errorf("Test %s failed. Page does not contain [%s]:\n%s", t.Name(), v, page)
+		}
+	}
+
+	if strings.Contains(page, "internal") {
+		t.Errorf("Test %s failed. Page contains hidden argument:\n%s", t.Name(), page)
+	}
+}
+
+func TestMutexGroup1(t *testing.T) {
+	p := NewParser("", "description")
+	json := p.Flag("", "json", nil)
+	yaml := p.Flag("", "yaml", nil)
+	p.NewMutexGroup(json, yaml)
+
+	err := p.Parse([]string{"progname", "--yaml"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+}
+
+func TestMutexGroupFail1(t *testing.T) {
+	p := NewParser("", "description")
+	json := p.Flag("", "json", nil)
+	_ = p.Flag("", "toml", nil)
+	cmd := p.NewCommand("print", "print description")
+	yaml := cmd.Flag("", "yaml", nil)
+	template := cmd.String("t", "template", nil)
+	cmd.NewMutexGroup(json, yaml, template)
+
+	err := p.Parse([]string{"progname", "print", "--json", "--toml", "-t", "x"})
+	errStr := "only one of [--json --template] may be specified"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+func TestMutexGroupPanic1(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Test %s failed. Unknown argument was accepted", t.Name())
+		}
+	}()
+
+	p := NewParser("", "description")
+	json := p.Flag("", "json", nil)
+	var yaml bool
+	p.NewMutexGroup(json, &yaml)
+}
+
+func TestRequiredIf1(t *testing.T) {
+	p := NewParser("", "description")
+	cert := p.String("c", "cert", nil)
+	_ = p.String("k", "key", &Options{RequiredIf: cert})
+
+	err := p.Parse([]string{"progname"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 	}
 }
 
-func TestCommandMixedArgs1(t *testing.T) {
-	val := 5150
-	pval := 316
-	testArgsList := [][]string{
-		{"progname", "cmd1", "--flag1", "--string-flag1", "test", "--int-flag1", strconv.Itoa(val), "--global-flag", "--global-string", "global test string", "--global-int", strconv.Itoa(pval)},
-		{"progname", "cmd2", "--global-string", "global test string", "--global-flag", "--global-int", strconv.Itoa(pval)},
+func TestRequiredIfFail1(t *testing.T) {
+	p := NewParser("", "description")
+	cert := p.String("c", "cert", nil)
+	_ = p.String("k", "key", &Options{RequiredIf: cert})
+
+	err := p.Parse([]string{"progname", "--cert", "cert.pem"})
+	errStr := "[-k|--key] is required when [-c|--cert] is provided"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 	}
+}
 
-	for _, testArgs := range testArgsList {
-		p := NewParser("progname", "description")
+func TestRequireOneOfFail1(t *testing.T) {
+	p := NewParser("", "description")
+	file := p.String("f", "file", nil)
+	url := p.String("u", "url", nil)
+	p.RequireOneOf(file, url)
 
-		cmd1 := p.NewCommand("cmd1", "cmd1 description")
-		cmd1flag1 := cmd1.Flag("f", "flag1", nil)
-		cmd1string1 := cmd1.String("s", "string-flag1", nil)
-		cmd1int1 := cmd1.Int("i", "int-flag1", nil)
+	err := p.Parse([]string{"progname"})
+	errStr := "one of [--file --url] is required"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
 
-		cmd2 := p.NewCommand("cmd2", "cmd2 description")
+	p = NewParser("", "description")
+	file = p.String("f", "file", nil)
+	url = p.String("u", "url", nil)
+	p.RequireOneOf(file, url)
 
-		pflag1 := p.Flag("", "global-flag", nil)
-		pstring1 := p.String("", "global-string", nil)
-		pint1 := p.Int("", "global-int", nil)
+	err = p.Parse([]string{"progname", "-u", "http://localhost"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+}
 
-		p.Parse(testArgs)
+func TestExactlyOneFail1(t *testing.T) {
+	testArgsList := map[string][]string{
+		"exactly one of [--json --yaml --text] is required": {"progname"},
+		"only one of [--json --text] allowed":               {"progname", "--json", "--text"},
+		"":                                                  {"progname", "--yaml"},
+	}
 
-		// Check global flags
-		if *pflag1 != true {
-			t.Errorf("Test %s failed with %s: pflag1: wanted [true], got [false]", t.Name(), testArgs[1])
-			return
-		}
-		if *pstring1 != "global test string" {
-			t.Errorf("Test %s failed with %s: pstring1: wanted [global test string], got [%s]", t.Name(), testArgs[1], *pstring1)
-			return
-		}
-		if *pint1 != pval {
-			t.Errorf("Test %s failed with %s: pint1: wanted [%d], got [%d]", t.Name(), testArgs[1], pval, *pint1)
-			return
-		}
+	for errStr, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		json := p.Flag("j", "json", nil)
+		yaml := p.Flag("y", "yaml", nil)
+		text := p.Flag("t", "text", nil)
+		p.ExactlyOne(json, yaml, text)
 
-		// Check commands
-		if cmd1.Happened() {
-			if *cmd1flag1 != true {
-				t.Errorf("Test %s failed with %s: flag1: wanted [true], got [false]", t.Name(), testArgs[1])
-				return
-			}
-			if *cmd1string1 != "test" {
-				t.Errorf("Test %s failed with %s: string1: wanted [test], got [%s]", t.Name(), testArgs[1], *cmd1string1)
-				return
-			}
-			if *cmd1int1 != val {
-				t.Errorf("Test %s failed with %s: int1: wanted [%d], got [%d]", t.Name(), testArgs[1], val, *cmd1int1)
-				return
-			}
-		}
-		if cmd2.Happened() {
-			if *cmd1flag1 != false {
-				t.Errorf("Test %s failed with %s: flag1: wanted [false], got [true]", t.Name(), testArgs[1])
-				return
-			}
-			if *cmd1string1 != "" {
-				t.Errorf("Test %s failed with %s: string1: wanted [], got [%s]", t.Name(), testArgs[1], *cmd1string1)
-				return
-			}
-			if *cmd1int1 != 0 {
-				t.Errorf("Test %s failed with %s: int1: wanted [0], got [%d]", t.Name(), testArgs[1], *cmd1int1)
-				return
-			}
-		}
-		if (cmd1.Happened() && cmd2.Happened()) || (!cmd1.Happened() && !cmd2.Happened()) {
-			t.Errorf("Test %s failed, either cmd1 and cmd2 or neither of them Happened()", t.Name())
-			return
+		err := p.Parse(testArgs)
+		if errStr == "" && err != nil {
+			t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		} else if errStr != "" && (err == nil || err.Error() != errStr) {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 		}
 	}
 }
 
-func TestOptsRequired1(t *testing.T) {
-	testArgs := []string{"progname", "--flag-arg1"}
+func TestAtMostOneFail1(t *testing.T) {
+	p := NewParser("", "description")
+	all := p.Flag("a", "all", nil)
+	none := p.Flag("n", "none", nil)
+	p.AtMostOne(all, none)
+
+	err := p.Parse([]string{"progname"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+
+	p = NewParser("", "description")
+	all = p.Flag("a", "all", nil)
+	none = p.Flag("n", "none", nil)
+	p.AtMostOne(all, none)
+
+	err = p.Parse([]string{"progname", "-a", "-n"})
+	errStr := "only one of [--all --none] may be specified"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+}
+
+var constraintsUsage = `Constraints:
+
+  Exactly one of [--json --yaml] is required
+  At most one of [--all --none] may be specified
+  At least one of [--file --url] is required
+
+`
+
+func TestUsageConstraints1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	p.AtMostOne(p.Flag("a", "all", nil), p.Flag("n", "none", nil))
+	p.RequireOneOf(p.String("f", "file", nil), p.String("u", "url", nil))
+	cmd := p.NewCommand("export", "")
+	cmd.ExactlyOne(cmd.Flag("j", "json", nil), cmd.Flag("y", "yaml", nil))
+
+	if usage := cmd.Usage(nil); !strings.HasSuffix(usage, constraintsUsage) {
+		t.Errorf("Test %s failed. Want suffix: [%s], got: [%s]", t.Name(), constraintsUsage, usage)
+	}
+
+	if usage := p.Usage(nil); strings.Contains(usage, "Exactly one") {
+		t.Errorf("Test %s failed. Usage of parser contains constraint of command: [%s]", t.Name(), usage)
+	}
+}
+
+func TestPrintUsageOnError1(t *testing.T) {
+	// Capture output printed to stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() {
+		os.Stderr = stderr
+	}()
+
+	p := NewParser("progname", "description")
+	p.PrintUsageOnError = true
+	cmd := p.NewCommand("run", "Run it")
+	_ = cmd.Int("c", "count", nil)
+
+	err = p.Parse([]string{"progname", "run", "-c", "x"})
+	w.Close()
+	output, _ := ioutil.ReadAll(r)
+	if err == nil {
+		t.Errorf("Test %s expected error, got nil", t.Name())
+		return
+	}
+
+	if usage := cmd.Usage(err); string(output) != usage {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), usage, output)
+	}
+}
+
+func TestListUnique1(t *testing.T) {
+	testArgs := []string{"progname", "--enable", "a", "-e", "b,c", "--enable", "a,d"}
 
 	p := NewParser("", "description")
-	_ = p.Flag("", "flag-arg1", nil)
-	_ = p.String("", "flag-arg2", &Options{Required: true})
+	_ = p.List("e", "enable", &Options{Unique: true, Separator: ","})
 
 	err := p.Parse(testArgs)
-	if err == nil {
-		t.Errorf("Test %s failed to detect required argument", t.Name())
+	errStr := `[-e|--enable] value "a" is provided more than once`
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	p = NewParser("", "description")
+	enable := p.List("e", "enable", &Options{DropDuplicates: true, Separator: ","})
+	ids := p.IntList("i", "id", &Options{DropDuplicates: true})
+	weights := p.FloatList("w", "weight", &Options{Unique: true, Separator: ","})
+
+	err = p.Parse(append(testArgs, "-i", "1", "-i", "01", "-i", "2", "-w", "0.5,1"))
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 		return
 	}
+
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(*enable, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *enable)
+	}
+
+	if want := []int{1, 2}; !reflect.DeepEqual(*ids, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *ids)
+	}
+
+	if want := []float64{0.5, 1}; !reflect.DeepEqual(*weights, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *weights)
+	}
+
+	p = NewParser("", "description")
+	_ = p.FloatList("w", "weight", &Options{Unique: true, Separator: ","})
+
+	err = p.Parse([]string{"progname", "-w", "0.50,.5"})
+	errStr = `[-w|--weight] value "0.5" is provided more than once`
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
 }
 
-func TestOptsRequired2(t *testing.T) {
-	testArgs := []string{"progname", "--flag-arg1"}
+func TestURLSimple1(t *testing.T) {
+	testArgs := []string{"progname", "--endpoint", "HTTPS://api.example.com/v1?x=1"}
 
 	p := NewParser("", "description")
-	_ = p.Flag("", "flag-arg1", nil)
-	_ = p.Int("", "int-arg1", &Options{Required: true})
+	endpoint := p.URL("e", "endpoint", &Options{Schemes: []string{"https"}})
+	proxy := p.URL("p", "proxy", &Options{Default: "socks5://localhost:1080"})
 
 	err := p.Parse(testArgs)
-	if err == nil {
-		t.Errorf("Test %s failed to detect required argument", t.Name())
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 		return
 	}
+
+	if endpoint.Scheme != "https" || endpoint.Host != "api.example.com" || endpoint.Path != "/v1" {
+		t.Errorf("Test %s failed. Got: [%s]", t.Name(), endpoint.String())
+	}
+
+	if proxy.String() != "socks5://localhost:1080" {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), "socks5://localhost:1080", proxy.String())
+	}
+
+	usage := "[-e|--endpoint <url>]"
+	if u := p.Usage(nil); !strings.Contains(u, usage) {
+		t.Errorf("Test %s failed. Usage does not contain [%s]: [%s]", t.Name(), usage, u)
+	}
 }
 
-var stropts = &Options{Validate: func(args []string) error {
-	if len(args) > 0 {
-		if args[0] != "pass" {
-			return errors.New("failure")
+func TestURLFail1(t *testing.T) {
+	testArgsList := map[string][]string{
+		`[-e|--endpoint] must be a URL with scheme https or http, got "ftp://example.com"`: {"progname", "-e", "ftp://example.com"},
+		`[-e|--endpoint] must be an absolute URL with host, got "example.com/path"`:        {"progname", "-e", "example.com/path"},
+		`[-e|--endpoint] must be a valid URL, got "http://[::1"`:                           {"progname", "-e", "http://[::1"},
+	}
+
+	for errStr, testArgs := range testArgsList {
+		p := NewParser("", "description")
+		_ = p.URL("e", "endpoint", &Options{Schemes: []string{"https", "http"}})
+
+		err := p.Parse(testArgs)
+		if err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 		}
 	}
-	return nil
-},
 }
 
-func TestOptsValidatePass1(t *testing.T) {
-	testArgsList := [][]string{
-		{"progname", "--string-flag1", "pass"},
-		{"progname", "--string-flag1", "fail"},
+func TestPrefixes1(t *testing.T) {
+	testArgs := []string{"progname", "/verbose", "/xf", "out.txt", "/n=3", "/name", "John", "-x"}
+
+	p := NewParser("progname", "description")
+	p.SetPrefixes("/", "/")
+	verbose := p.Flag("v", "verbose", nil)
+	extract := p.Flag("x", "extract", nil)
+	file := p.String("f", "file", nil)
+	count := p.Int("n", "count", nil)
+	name := p.String("a", "name", nil)
+	positional := p.Positional("value", nil)
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
 	}
 
-	for _, testArgs := range testArgsList {
-		p := NewParser("progname", "")
+	if !*verbose || !*extract || *file != "out.txt" || *count != 3 || *name != "John" || *positional != "-x" {
+		t.Errorf("Test %s failed. Got: verbose [%t], extract [%t], file [%s], count [%d], name [%s], value [%s]",
+			t.Name(), *verbose, *extract, *file, *count, *name, *positional)
+	}
 
-		string1 := p.String("", "string-flag1", stropts)
+	want := "usage: progname [/h|/help] [/v|/verbose] [/x|/extract] [/f|/file \"<value>\"]"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) || !strings.Contains(usage, "  /h  /help     Print help information\n") {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
 
-		err := p.Parse(testArgs)
+func TestPrefixes2(t *testing.T) {
+	p := NewParser("progname", "description")
+	p.SetPrefixes("+", "++")
+	verbose := p.FlagCounter("v", "verbose", nil)
+	force := p.Flag("f", "force", &Options{Negatable: true, Default: true})
+	_ = p.Int("c", "count", nil)
 
-		if testArgs[2] == "pass" {
-			if err != nil {
-				t.Errorf("Test %s failed on %s with err: %s", t.Name(), testArgs[2], err.Error())
-				return
-			}
+	err := p.Parse([]string{"progname", "+vv", "++verbose", "++no-force", "-v"})
+	errStr := "too many arguments"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
 
-			if *string1 != "pass" {
-				t.Errorf("Test %s failed on %s; string1 expected [%s], got [%s]", t.Name(), testArgs[2], testArgs[2], *string1)
-				return
-			}
-		} else {
-			if err == nil {
-				t.Errorf("Test %s failed to validate argument (should return error)", t.Name())
-				return
-			}
-		}
+	if *verbose != 3 || *force {
+		t.Errorf("Test %s failed. Got: verbose [%d], force [%t]", t.Name(), *verbose, *force)
+	}
+
+	p = NewParser("progname", "description")
+	p.SetPrefixes("+", "++")
+	_ = p.Int("c", "count", nil)
+	_ = p.Flag("", "verbose", nil)
+
+	err = p.Parse([]string{"progname", "++verbos"})
+	errStr = "unknown argument [++verbos], did you mean [++verbose]?"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	}
+
+	p = NewParser("progname", "description")
+	p.SetPrefixes("+", "++")
+	_ = p.Int("c", "count", nil)
+
+	err = p.Parse([]string{"progname", "+c", "x"})
+	errStr = `[+c|++count] must be an integer, got "x"`
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 	}
 }
 
-func TestOptsValidatePass2(t *testing.T) {
-	val1 := 5150
-	val2 := 316
+func TestReset1(t *testing.T) {
+	p := NewParser("progname", "description")
+	p.PassThroughUnknown = true
+	verbose := p.FlagCounter("v", "verbose", &Options{MaxOccurrences: 2})
+	level := p.VerbosityLevel("l", "level", []string{"warn", "info"}, nil)
+	mode := p.SelectorIndex("m", "mode", []string{"fast", "slow"}, nil)
+	name := p.String("n", "name", &Options{Default: "none"})
+	tags := p.List("t", "tag", nil)
+	labels := p.StringMap("", "label", nil)
+	cmd := p.NewCommand("run", "")
+	force := cmd.Flag("f", "force", nil)
+	_ = p.NewCommand("stop", "")
+
+	err := p.Parse([]string{"progname", "run", "-vv", "-l", "-m", "slow", "-n", "x", "-t", "a", "--label", "a=b", "-f", "--foo", "--", "rest"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
 
-	var intopts = &Options{Validate: func(args []string) error {
-		if len(args) > 0 {
-			myval, err := strconv.Atoi(args[0])
-			if err != nil {
-				return errors.New("conversion failure")
-			} else if myval != val1 {
-				return errors.New("failure")
-			}
-		}
-		return nil
-	},
+	p.Reset()
+
+	if *verbose != 0 || *level != "warn" || *mode != -1 || *name != "" || len(*tags) != 0 || len(*labels) != 0 || *force {
+		t.Errorf("Test %s failed. Results were not reset", t.Name())
 	}
 
-	testArgsList := [][]string{
-		{"progname", "--int-flag1", strconv.Itoa(val1)},
-		{"progname", "--int-flag1", strconv.Itoa(val2)},
+	if cmd.Happened() || p.WasSet(name) || len(p.Remaining()) != 0 || len(p.Unknown()) != 0 {
+		t.Errorf("Test %s failed. State of parsing was not reset", t.Name())
 	}
 
-	for _, testArgs := range testArgsList {
-		p := NewParser("progname", "")
+	err = p.Parse([]string{"progname", "stop", "-vv", "-t", "b"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
 
-		int1 := p.Int("", "int-flag1", intopts)
+	if *verbose != 2 || *name != "none" || !reflect.DeepEqual(*tags, []string{"b"}) || cmd.Happened() {
+		t.Errorf("Test %s failed. Got: verbose [%d], name [%s], tags [%v], run [%t]", t.Name(), *verbose, *name, *tags, cmd.Happened())
+	}
+}
 
-		err := p.Parse(testArgs)
+func TestUsageMetavar1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	_ = p.Int("c", "count", &Options{Metavar: "<N>"})
+	_ = p.String("f", "file", &Options{Metavar: "PATH"})
+	_ = p.Selector("m", "mode", []string{"fast", "slow"}, &Options{Metavar: "MODE"})
+	_ = p.List("t", "tag", &Options{Metavar: "TAG"})
+	_ = p.Flag("v", "verbose", &Options{Metavar: "IGNORED"})
+
+	want := "usage: prog [-h|--help] [-c|--count <N>] [-f|--file PATH] [-m|--mode MODE]\n" +
+		"            [-t|--tag TAG [-t|--tag TAG ...]] [-v|--verbose]\n"
+	if usage := p.Usage(nil); !strings.HasPrefix(usage, want) {
+		t.Errorf("Test %s failed. Want prefix: [%s], got: [%s]", t.Name(), want, usage)
+	}
+}
 
-		if testArgs[2] == strconv.Itoa(val1) {
-			if err != nil {
-				t.Errorf("Test %s failed on %s with err: %s", t.Name(), testArgs[2], err.Error())
-				return
-			}
+func TestRequireEquals1(t *testing.T) {
+	testArgs := []string{"progname", "--token=secret", "-l=a", "--list=b"}
+
+	p := NewParser("", "description")
+	token := p.String("t", "token", &Options{RequireEquals: true})
+	list := p.List("l", "list", &Options{RequireEquals: true})
+
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if *token != "secret" {
+		t.Errorf("Test %s failed. Want: [secret], got: [%s]", t.Name(), *token)
+	}
+	if len(*list) != 2 || (*list)[0] != "a" || (*list)[1] != "b" {
+		t.Errorf("Test %s failed. Want: [[a b]], got: [%v]", t.Name(), *list)
+	}
+}
 
-			if *int1 != val1 {
-				t.Errorf("Test %s failed on %s; int1 expected [%d], got [%d]", t.Name(), testArgs[2], val1, *int1)
-				return
-			}
-		} else {
-			if err == nil {
-				t.Errorf("Test %s failed to validate argument (should return error)", t.Name())
-				return
-			}
+func TestRequireEquals2(t *testing.T) {
+	failures := map[string][]string{
+		"space separated": {"progname", "--token", "secret"},
+		"attached short":  {"progname", "-tsecret"},
+		"short separated": {"progname", "-t", "secret"},
+	}
+	for name, testArgs := range failures {
+		p := NewParser("", "description")
+		_ = p.String("t", "token", &Options{RequireEquals: true})
+
+		err := p.Parse(testArgs)
+		want := "[-t|--token] value must be attached with \"=\", as in --token=<value>"
+		if err == nil || err.Error() != want {
+			t.Errorf("Test %s %s failed. Want: [%s], got: [%v]", t.Name(), name, want, err)
 		}
 	}
 }
 
-var pUsage = `usage: verylongprogname <Command> [-h|--help] [-s|--verylongstring-flag1
-                        "<value>"] [-i|--integer-flag1 <integer>]
+func TestIntBase1(t *testing.T) {
+	testArgs := []string{"progname", "--mask", "0xFF", "--bits", "0b1010", "--mode", "0o17", "--size", "-0x10", "--ids", "010", "--ids", "0B11"}
 
-                        prog description
+	p := NewParser("", "description")
+	mask := p.Int("", "mask", nil)
+	bits := p.Uint("", "bits", nil)
+	mode := p.Int("", "mode", nil)
+	size := p.Int64("", "size", nil)
+	ids := p.IntList("", "ids", nil)
 
-Commands:
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if *mask != 255 || *bits != 10 || *mode != 15 || *size != -16 {
+		t.Errorf("Test %s failed. Want: [255 10 15 -16], got: [%d %d %d %d]", t.Name(), *mask, *bits, *mode, *size)
+	}
+	if len(*ids) != 2 || (*ids)[0] != 10 || (*ids)[1] != 3 {
+		t.Errorf("Test %s failed. Want: [[10 3]], got: [%v]", t.Name(), *ids)
+	}
+}
 
-  veryverylongcmd1  cmd1 description
-  cmd2              cmd2 description
+func TestIntBase2(t *testing.T) {
+	failures := []struct {
+		args []string
+		opts *Options
+		err  string
+	}{
+		{[]string{"progname", "--mask", "0xFF"}, &Options{Base: 10}, `[--mask] must be an integer, got "0xFF"`},
+		{[]string{"progname", "--mask", "12"}, &Options{Base: 2}, `[--mask] must be a base 2 integer, got "12"`},
+		{[]string{"progname", "--mask", "0x-1"}, nil, `[--mask] must be an integer, got "0x-1"`},
+		{[]string{"progname", "--mask", "0x1FFFFFFFFFFFFFFFF"}, nil, `[--mask] integer is out of range, got "0x1FFFFFFFFFFFFFFFF"`},
+	}
+	for _, v := range failures {
+		p := NewParser("", "description")
+		_ = p.Int64("", "mask", v.opts)
 
-Arguments:
+		err := p.Parse(v.args)
+		if err == nil || err.Error() != v.err {
+			t.Errorf("Test %s failed. Want: [%s], got: [%v]", t.Name(), v.err, err)
+		}
+	}
 
-  -h  --help                  Print help information
-  -s  --verylongstring-flag1  string1 description
-  -i  --integer-flag1         integer1 description
+	p := NewParser("", "description")
+	mask := p.Int("", "mask", &Options{Base: 16})
+	if err := p.Parse([]string{"progname", "--mask", "ff"}); err != nil || *mask != 255 {
+		t.Errorf("Test %s failed. Want: [255], got: [%d] with error [%v]", t.Name(), *mask, err)
+	}
+}
 
-`
+func TestOnParse1(t *testing.T) {
+	testArgs := []string{"progname", "--verbose", "-n", "3", "--config=app.conf", "input", "--tag", "a", "--tag", "b"}
 
-var cmd1Usage = `usage: verylongprogname veryverylongcmd1 [-f|--verylongflag1]
-                        -a|--verylongflagA [-h|--help]
-                        [-s|--verylongstring-flag1 "<value>"]
-                        [-i|--integer-flag1 <integer>]
+	calls := make([]string, 0)
+	record := func(name string) func(string) error {
+		return func(value string) error {
+			calls = append(calls, name+":"+value)
+			return nil
+		}
+	}
 
-                        cmd1 description
+	p := NewParser("", "description")
+	_ = p.List("t", "tag", &Options{OnParse: record("tag")})
+	_ = p.Int("n", "count", &Options{OnParse: record("count")})
+	_ = p.String("c", "config", &Options{OnParse: record("config")})
+	_ = p.Flag("v", "verbose", &Options{OnParse: record("verbose")})
+	_ = p.Positional("input", &Options{OnParse: record("input")})
 
-Arguments:
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	want := "verbose: count:3 config:app.conf tag:a tag:b input:input"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, got)
+	}
+}
 
-  -f  --verylongflag1         flag1 description
-  -a  --verylongflagA         flag1 description
-  -h  --help                  Print help information
-  -s  --verylongstring-flag1  string1 description
-  -i  --integer-flag1         integer1 description
+func TestOnParse2(t *testing.T) {
+	testArgs := []string{"progname", "--config", "missing.conf"}
 
-`
+	failure := errors.New("cannot load config")
 
-var cmd2Usage = `usage: verylongprogname cmd2 [-h|--help] [-s|--verylongstring-flag1 "<value>"]
-                        [-i|--integer-flag1 <integer>]
+	p := NewParser("", "description")
+	_ = p.String("c", "config", &Options{OnParse: func(value string) error {
+		return failure
+	}})
 
-                        cmd2 description
+	err := p.Parse(testArgs)
+	if err != failure {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), failure, err)
+	}
+}
 
-Arguments:
+func TestOnParse3(t *testing.T) {
+	os.Setenv("ARGPARSE_TEST_LEVEL", "debug")
+	defer os.Unsetenv("ARGPARSE_TEST_LEVEL")
 
-  -h  --help                  Print help information
-  -s  --verylongstring-flag1  string1 description
-  -i  --integer-flag1         integer1 description
+	testArgs := []string{"progname", "--a", "x", "--b", "y"}
 
-`
+	calls := make([]string, 0)
+	record := func(name string) func(string) error {
+		return func(value string) error {
+			calls = append(calls, name+":"+value)
+			return nil
+		}
+	}
 
-func TestUsageSimple1(t *testing.T) {
-	p := NewParser("verylongprogname", "prog description")
+	// Calls follow command line rather than order arguments were created in, value from environment comes last
+	p := NewParser("", "description")
+	_ = p.String("", "level", &Options{EnvVar: "ARGPARSE_TEST_LEVEL", OnParse: record("level")})
+	_ = p.String("", "b", &Options{OnParse: record("b")})
+	_ = p.String("", "a", &Options{OnParse: record("a")})
+	_ = p.String("", "name", &Options{Default: "none", OnParse: record("name")})
 
-	cmd1 := p.NewCommand("veryverylongcmd1", "cmd1 description")
-	_ = cmd1.Flag("f", "verylongflag1", &Options{Help: "flag1 description"})
-	_ = cmd1.Flag("a", "verylongflagA", &Options{Required: true, Help: "flag1 description"})
-	_ = p.String("s", "verylongstring-flag1", &Options{Help: "string1 description"})
-	_ = p.Int("i", "integer-flag1", &Options{Help: "integer1 description"})
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	want := "a:x b:y level:debug"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, got)
+	}
+}
 
-	cmd2 := p.NewCommand("cmd2", "cmd2 description")
+func TestDeprecated1(t *testing.T) {
+	testArgs := []string{"progname", "--old", "a", "--old", "b", "--no-legacy"}
 
-	p.Parse(os.Args)
+	var warnings bytes.Buffer
+	p := NewParser("", "description")
+	p.WarningOutput = &warnings
+	old := p.List("", "old", &Options{Deprecated: "use --new instead", Hidden: true})
+	legacy := p.Flag("", "legacy", &Options{Deprecated: "it has no effect", Negatable: true, Default: true})
+	_ = p.List("", "new", nil)
 
-	if pUsage != p.Usage(nil) {
-		t.Errorf("%s", p.Usage(nil))
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
 	}
-	if cmd1Usage != cmd1.Usage(nil) {
-		t.Errorf("%s", cmd1.Usage(nil))
+	if len(*old) != 2 || *legacy {
+		t.Errorf("Test %s failed. Want: [[a b] false], got: [%v %v]", t.Name(), *old, *legacy)
 	}
-	if cmd2Usage != cmd2.Usage(nil) {
-		t.Errorf("%s", cmd2.Usage(nil))
+	want := "--old is deprecated, use --new instead\n--legacy is deprecated, it has no effect\n"
+	if warnings.String() != want {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, warnings.String())
+	}
+	if strings.Contains(p.Usage(nil), "--old") {
+		t.Errorf("Test %s failed. Usage contains hidden argument:\n%s", t.Name(), p.Usage(nil))
 	}
 }
 
-func TestUsageHidden1(t *testing.T) {
-	p := NewParser("verylongprogname", "prog description")
+func TestArguments1(t *testing.T) {
+	p := NewParser("prog", "program description")
+	_ = p.Flag("v", "verbose", &Options{Help: "Print more"})
+	_ = p.Selector("l", "level", []string{"info", "debug"}, &Options{Required: true})
+	_ = p.Int("", "count", &Options{Default: 3, Hidden: true})
+	_ = p.Positional("input", nil)
+	remote := p.NewCommand("remote", "remote description")
+	_ = remote.IntList("i", "id", nil)
+
+	want := []ArgInfo{
+		{Short: "h", Long: "help", Type: "help", Help: "Print help information"},
+		{Short: "v", Long: "verbose", Type: "flag", Help: "Print more"},
+		{Short: "l", Long: "level", Type: "selector", Required: true, Selector: []string{"info", "debug"}},
+		{Long: "count", Type: "int", Hidden: true, Default: 3},
+		{Long: "input", Type: "string", Positional: true},
+	}
+	if got := p.Arguments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Test %s failed. Want: [%+v], got: [%+v]", t.Name(), want, got)
+	}
 
-	cmd1 := p.NewCommand("veryverylongcmd1", "cmd1 description")
-	_ = cmd1.Flag("f", "verylongflag1", &Options{Help: "flag1 description"})
-	_ = cmd1.Flag("a", "verylongflagA", &Options{Required: true, Help: "flag1 description"})
-	_ = p.String("s", "verylongstring-flag1", &Options{Help: "string1 description"})
-	_ = p.Int("i", "integer-flag1", &Options{Help: "integer1 description"})
-	_ = p.Int("i2", "integer-flag2", &Options{Help: DisableDescription})
+	want = []ArgInfo{{Short: "i", Long: "id", Type: "int-list"}}
+	if got := remote.Arguments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Test %s failed. Want: [%+v], got: [%+v]", t.Name(), want, got)
+	}
+}
 
-	_ = p.NewCommand("cmd2", "cmd2 description")
+func TestSetOutput1(t *testing.T) {
+	var output bytes.Buffer
 
-	cmd3 := p.NewCommand("cmd3", DisableDescription)
-	_ = cmd3.Flag("f", "verylongflag1", &Options{Help: "flag1 description"})
-	_ = cmd3.Flag("a", "verylongflagA", &Options{Required: true, Help: "flag1 description"})
+	p := NewParser("progname", "description")
+	p.ExitFunc = func(int) {}
+	p.SetOutput(&output)
+	cmd := p.NewCommand("run", "Run it")
+	_ = cmd.Int("c", "count", nil)
 
-	p.Parse(os.Args)
+	err := p.Parse([]string{"progname", "run", "--help"})
+	if err != ErrHelpRequested {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), ErrHelpRequested, err)
+	}
+	if usage := cmd.Usage(nil); output.String() != usage {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), usage, output.String())
+	}
 
-	if pUsage != p.Usage(nil) {
-		t.Errorf("%s", p.Usage(nil))
+	output.Reset()
+	p = NewParser("progname", "description")
+	p.PrintUsageOnError = true
+	p.SetOutput(&output)
+	_ = p.Int("c", "count", nil)
+
+	err = p.Parse([]string{"progname", "-c", "x"})
+	if err == nil {
+		t.Errorf("Test %s expected error, got nil", t.Name())
+		return
 	}
-	if cmd1Usage != cmd1.Usage(nil) {
-		t.Errorf("%s", cmd1.Usage(nil))
+	if usage := p.Usage(err); output.String() != usage {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), usage, output.String())
 	}
 }
 
-func TestStringMissingArgFail(t *testing.T) {
-	testArgs := []string{"progname", "-s"}
-
-	p := NewParser("progname", "Prog description")
+func TestParseLoose1(t *testing.T) {
+	p := NewParser("", "")
+	v := p.Flag("v", "verbose", nil)
+	cmd := p.NewCommand("run", "")
+	name := cmd.String("n", "name", &Options{Required: true})
 
-	_ = p.String("s", "string", &Options{Required: true, Help: "A test string"})
+	report, err := p.ParseLoose([]string{"progname", "run", "--plugin-opt=x", "-n", "a", "--other", "val", "-vz", "--empty=", "--", "--rest"})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	if *name != "a" || !*v {
+		t.Errorf("Test %s got name %q and verbose %t", t.Name(), *name, *v)
+	}
+	wantUnknown := []UnknownArg{
+		{Arg: "--plugin-opt", Value: "x", HasValue: true, Position: 2},
+		{Arg: "--other", Position: 5},
+		{Arg: "-z", Position: 7},
+		{Arg: "--empty", HasValue: true, Position: 8},
+	}
+	if !reflect.DeepEqual(report.Unknown, wantUnknown) {
+		t.Errorf("Test %s expected unknown %+v, got %+v", t.Name(), wantUnknown, report.Unknown)
+	}
+	wantExtra := []UnknownArg{{Arg: "val", Position: 6}}
+	if !reflect.DeepEqual(report.Extra, wantExtra) {
+		t.Errorf("Test %s expected extra %+v, got %+v", t.Name(), wantExtra, report.Extra)
+	}
+	if !reflect.DeepEqual(p.Remaining(), []string{"--rest"}) {
+		t.Errorf("Test %s expected remaining [--rest], got %v", t.Name(), p.Remaining())
+	}
 
-	err := p.Parse(testArgs)
+	// Problems with known arguments are still errors, and unknown ones are reported anyway
+	p.Reset()
+	report, err = p.ParseLoose([]string{"progname", "run", "--other"})
+	if err == nil || err.Error() != "[-n|--name] is required" {
+		t.Errorf("Test %s expected required error, got %v", t.Name(), err)
+	}
+	if len(report.Unknown) != 1 || report.Unknown[0].Arg != "--other" {
+		t.Errorf("Test %s expected --other to be reported, got %+v", t.Name(), report.Unknown)
+	}
 
-	if err != nil {
-		// Test should pass on failure
-		if err.Error() != "not enough arguments for -s|--string" {
-			t.Errorf("Test %s failed: expected error [%s], got error [%s]", t.Name(), "not enough arguments for -s|--string", err.Error())
-		}
+	// Parse is strict again afterwards
+	p.Reset()
+	if err := p.Parse([]string{"progname", "run", "-n", "a", "--other"}); err == nil {
+		t.Errorf("Test %s expected error for unknown argument", t.Name())
 	}
 }
 
-func TestIntMissingArgFail(t *testing.T) {
-	testArgs := []string{"progname", "-i"}
-
-	p := NewParser("progname", "Prog description")
+func TestOnUnknown1(t *testing.T) {
+	testArgs := []string{"progname", "--colour=red", "-v", "-x", "input"}
 
-	_ = p.Int("i", "integer", &Options{Required: true, Help: "A test integer"})
+	unknown := make([]string, 0)
+	p := NewParser("", "description")
+	p.OnUnknown = func(flag string) error {
+		unknown = append(unknown, flag)
+		return nil
+	}
+	verbose := p.Flag("v", "verbose", nil)
+	input := p.Positional("input", nil)
 
 	err := p.Parse(testArgs)
-
 	if err != nil {
-		// Test should pass on failure
-		errStr := "not enough arguments for -i|--integer"
-		if err.Error() != errStr {
-			t.Errorf("Test %s failed: expected error [%s], got error [%s]", t.Name(), errStr, err.Error())
-		}
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if !*verbose || *input != "input" {
+		t.Errorf("Test %s failed. Want: [true input], got: [%v %s]", t.Name(), *verbose, *input)
+	}
+	if want := []string{"--colour=red", "-x"}; !reflect.DeepEqual(unknown, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, unknown)
+	}
+	if len(p.Unknown()) != 0 {
+		t.Errorf("Test %s failed. Want no unknown arguments, got: [%v]", t.Name(), p.Unknown())
 	}
 }
 
-func TestFlagDefaultValuePass(t *testing.T) {
-	testArgs := []string{"progname"}
-
-	p := NewParser("progname", "Prog description")
+func TestOnUnknown2(t *testing.T) {
+	testArgs := []string{"progname", "--colour", "red"}
 
-	f := p.Flag("f", "flag", &Options{Default: true})
+	failure := errors.New("unsupported flag")
+	p := NewParser("", "description")
+	p.OnUnknown = func(flag string) error {
+		if flag == "--colour" {
+			return failure
+		}
+		return nil
+	}
 
 	err := p.Parse(testArgs)
+	if err != failure {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), failure, err)
+	}
+}
 
-	// Should fail on failure
+func TestCommandRequired1(t *testing.T) {
+	newParser := func() *Parser {
+		p := NewParser("", "description")
+		_ = p.String("c", "config", &Options{Required: true})
+		add := p.NewCommand("add", "Add a remote")
+		_ = add.String("u", "url", &Options{Required: true})
+		remove := p.NewCommand("remove", "Remove a remote")
+		_ = remove.String("n", "name", &Options{Required: true})
+		return p
+	}
+
+	p := newParser()
+	err := p.Parse([]string{"progname", "remove", "-c", "app.conf", "--name", "origin"})
 	if err != nil {
-		t.Error(err.Error())
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 	}
 
-	// Should fail if not true
-	if *f != true {
-		t.Errorf("expected [true], got [%t]", *f)
+	failures := map[string][]string{
+		"[-u|--url] is required":    {"progname", "add", "-c", "app.conf"},
+		"[-n|--name] is required":   {"progname", "remove", "-c", "app.conf"},
+		"[-c|--config] is required": {"progname", "add", "--url", "https://example.com"},
+	}
+	for errStr, testArgs := range failures {
+		p := newParser()
+		err := p.Parse(testArgs)
+		if err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		}
 	}
 }
 
-func TestFlagDefaultValueFail(t *testing.T) {
-	testArgs := []string{"progname"}
-
-	p := NewParser("progname", "Prog description")
+func TestListOrder1(t *testing.T) {
+	testArgs := []string{"progname", "run", "--a", "x", "--b", "-I", "inc1", "--a", "y", "-Iinc2", "--include=inc3,inc4", "-cI", "inc5"}
 
-	_ = p.Flag("f", "flag", &Options{Default: "string"})
+	p := NewParser("", "description")
+	a := p.List("", "a", nil)
+	_ = p.Flag("b", "b", nil)
+	_ = p.Flag("c", "c", nil)
+	include := p.List("I", "include", &Options{Separator: ","})
+	_ = p.NewCommand("run", "Run it")
 
 	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if want := []string{"x", "y"}; !reflect.DeepEqual(*a, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *a)
+	}
+	if want := []string{"inc1", "inc2", "inc3", "inc4", "inc5"}; !reflect.DeepEqual(*include, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *include)
+	}
+}
 
-	// Should pass on failure
-	if err == nil || err.Error() != "cannot use default type [string] as type [bool]" {
-		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [string] as type [bool]", err)
+func TestConsumeRaw1(t *testing.T) {
+	testCases := []struct {
+		args    []string
+		expr    string
+		quiet   bool
+		verbose bool
+	}{
+		{[]string{"progname", "-e", "-n", "-v"}, "-n", false, true},
+		{[]string{"progname", "-n", "-ve", "--help"}, "--help", true, true},
+		{[]string{"progname", "--expression", "--"}, "--", false, false},
+		{[]string{"progname", "-e", "--", "-n"}, "--", true, false},
+		{[]string{"progname", "--expression=-v", "-n"}, "-v", true, false},
+	}
+	for _, tc := range testCases {
+		p := NewParser("", "")
+		quiet := p.Flag("n", "quiet", nil)
+		verbose := p.Flag("v", "verbose", nil)
+		expr := p.String("e", "expression", &Options{ConsumeRaw: true})
+		if err := p.Parse(tc.args); err != nil {
+			t.Errorf("Test %s failed with error: %s for %v", t.Name(), err.Error(), tc.args)
+			continue
+		}
+		if *expr != tc.expr || *quiet != tc.quiet || *verbose != tc.verbose {
+			t.Errorf("Test %s got %q, %t and %t for %v", t.Name(), *expr, *quiet, *verbose, tc.args)
+		}
+	}
+
+	p := NewParser("", "")
+	p.String("e", "expression", &Options{ConsumeRaw: true, RejectFlagLikeValues: true})
+	if err := p.Validate(); err == nil || err.Error() != "[-e|--expression] cannot have both ConsumeRaw and RejectFlagLikeValues" {
+		t.Errorf("Test %s expected Validate error, got %v", t.Name(), err)
 	}
 }
 
-func TestStringDefaultValuePass(t *testing.T) {
-	testArgs := []string{"progname"}
-	testString := "test string"
+func TestRejectFlagLikeValues1(t *testing.T) {
+	newParser := func() *Parser {
+		p := NewParser("", "description")
+		_ = p.String("o", "output", &Options{RejectFlagLikeValues: true})
+		_ = p.Flag("v", "verbose", nil)
+		_ = p.Flag("", "color", &Options{Negatable: true})
+		_ = p.String("", "name", nil)
+		return p
+	}
 
-	p := NewParser("progname", "Prog description")
+	failures := map[string]string{
+		"--verbose":  `[-o|--output] got value "--verbose" that is an argument name, value is probably missing`,
+		"-v":         `[-o|--output] got value "-v" that is an argument name, value is probably missing`,
+		"--no-color": `[-o|--output] got value "--no-color" that is an argument name, value is probably missing`,
+	}
+	for value, errStr := range failures {
+		p := newParser()
+		err := p.Parse([]string{"progname", "--output", value})
+		if err == nil || err.Error() != errStr {
+			t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+		}
+	}
 
-	s := p.String("s", "string", &Options{Default: testString})
+	for _, testArgs := range [][]string{
+		{"progname", "--output", "-", "--name", "-v"},
+		{"progname", "--output", "--unknown"},
+		{"progname", "--output=--verbose"},
+	} {
+		p := newParser()
+		if err := p.Parse(testArgs); err != nil {
+			t.Errorf("Test %s failed for %v with error: %s", t.Name(), testArgs, err.Error())
+		}
+	}
+}
 
-	err := p.Parse(testArgs)
+func TestHelpCommand1(t *testing.T) {
+	newParser := func(output io.Writer) (*Parser, *Command, *Command) {
+		p := NewParser("prog", "description")
+		p.ExitFunc = func(int) {}
+		p.SetOutput(output)
+		remote := p.NewCommand("remote", "Manage remotes")
+		add := remote.NewCommand("add", "Add a remote")
+		_ = add.String("u", "url", &Options{Required: true})
+		return p, remote, add
+	}
 
-	// Should fail on failure
-	if err != nil {
-		t.Error(err.Error())
+	for _, testArgs := range [][]string{
+		{"prog", "--help", "remote", "add"},
+		{"prog", "remote", "-h", "add"},
+		{"prog", "remote", "--help=add"},
+		{"prog", "remote", "add", "--help"},
+	} {
+		var output bytes.Buffer
+		p, _, add := newParser(&output)
+		err := p.Parse(testArgs)
+		if err != ErrHelpRequested {
+			t.Errorf("Test %s failed for %v. Want: [%v], got: [%v]", t.Name(), testArgs, ErrHelpRequested, err)
+		}
+		if usage := add.Usage(nil); output.String() != usage {
+			t.Errorf("Test %s failed for %v. Want: [%s], got: [%s]", t.Name(), testArgs, usage, output.String())
+		}
 	}
 
-	// Should fail if not true
-	if *s != testString {
-		t.Errorf("expected [string], got [%T]", *s)
+	var output bytes.Buffer
+	p, remote, _ := newParser(&output)
+	err := p.Parse([]string{"prog", "--help", "remote", "--verbose"})
+	if usage := remote.Usage(nil); err != ErrHelpRequested || output.String() != usage {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s] with error [%v]", t.Name(), usage, output.String(), err)
 	}
-}
 
-func TestStringDefaultValueFail(t *testing.T) {
-	testArgs := []string{"progname"}
+	output.Reset()
+	p, _, _ = newParser(&output)
+	err = p.Parse([]string{"prog", "--help=deploy"})
+	if usage := p.Usage("unknown command [deploy]"); err != ErrHelpRequested || output.String() != usage {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s] with error [%v]", t.Name(), usage, output.String(), err)
+	}
+}
 
-	p := NewParser("progname", "Prog description")
+func TestBoolPtr1(t *testing.T) {
+	testArgs := []string{"progname", "-fv", "--color=false", "--no-cache"}
 
-	_ = p.String("s", "string", &Options{Default: false})
+	p := NewParser("", "description")
+	force := p.BoolPtr("f", "force", nil)
+	verbose := p.Flag("v", "verbose", nil)
+	color := p.BoolPtr("", "color", nil)
+	cache := p.BoolPtr("", "cache", &Options{Negatable: true})
+	unset := p.BoolPtr("", "unset", nil)
+	def := p.BoolPtr("", "default", &Options{Default: true})
 
 	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if *force == nil || !**force || !*verbose {
+		t.Errorf("Test %s failed. Want: [true true], got: [%v %v]", t.Name(), *force, *verbose)
+	}
+	if *color == nil || **color {
+		t.Errorf("Test %s failed. Want color: [false], got: [%v]", t.Name(), *color)
+	}
+	if *cache == nil || **cache {
+		t.Errorf("Test %s failed. Want cache: [false], got: [%v]", t.Name(), *cache)
+	}
+	if *unset != nil {
+		t.Errorf("Test %s failed. Want unset: [nil], got: [%v]", t.Name(), **unset)
+	}
+	if *def == nil || !**def {
+		t.Errorf("Test %s failed. Want default: [true], got: [%v]", t.Name(), *def)
+	}
 
-	// Should pass on failure
-	if err == nil || err.Error() != "cannot use default type [bool] as type [string]" {
-		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	want := "[--cache|--no-cache]"
+	if usage := p.Usage(nil); !strings.Contains(usage, want) || !strings.Contains(usage, "[-f|--force]") {
+		t.Errorf("Test %s failed. Usage does not contain [%s]:\n%s", t.Name(), want, usage)
 	}
-}
 
-func TestIntDefaultValuePass(t *testing.T) {
-	testArgs := []string{"progname"}
-	testVal := 5150
+	p.Reset()
+	if *force != nil {
+		t.Errorf("Test %s failed. Want force after Reset: [nil], got: [%v]", t.Name(), **force)
+	}
+}
 
-	p := NewParser("progname", "Prog description")
+func TestNegativeNumbers1(t *testing.T) {
+	testArgs := []string{"progname", "-v", "-5", "--offset", "-0.3", "-1e3", "-x", "-.5"}
 
-	i := p.Int("i", "integer", &Options{Default: testVal})
+	p := NewParser("", "description")
+	verbose := p.Flag("v", "verbose", nil)
+	flag3 := p.Flag("3", "three", nil)
+	offset := p.Float("", "offset", nil)
+	numbers := p.PositionalList("numbers", nil)
+	x := p.Flag("x", "ex", nil)
 
 	err := p.Parse(testArgs)
-
-	// Should fail on failure
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
 	}
-
-	// Should fail if not true
-	if *i != testVal {
-		t.Errorf("expected [%d], got [%d]", testVal, *i)
+	if !*verbose || !*x || *flag3 || *offset != -0.3 {
+		t.Errorf("Test %s failed. Want: [true true false -0.3], got: [%v %v %v %v]", t.Name(), *verbose, *x, *flag3, *offset)
+	}
+	if want := []string{"-5", "-1e3", "-.5"}; !reflect.DeepEqual(*numbers, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *numbers)
 	}
 }
 
-func TestIntDefaultValueFail(t *testing.T) {
-	testArgs := []string{"progname"}
-
-	p := NewParser("progname", "Prog description")
+func TestNegativeNumbers2(t *testing.T) {
+	testArgs := []string{"progname", "-1", "-2"}
 
-	_ = p.Int("i", "integer", &Options{Default: "fail"})
+	p := NewParser("", "description")
+	one := p.Flag("1", "one", nil)
+	numbers := p.PositionalList("numbers", nil)
 
 	err := p.Parse(testArgs)
-
-	// Should pass on failure
-	if err == nil || err.Error() != "cannot use default type [string] as type [int]" {
-		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if !*one {
+		t.Errorf("Test %s failed. Want short name [-1] to be a flag", t.Name())
+	}
+	if want := []string{"-2"}; !reflect.DeepEqual(*numbers, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *numbers)
 	}
 }
 
-func TestFileDefaultValuePass(t *testing.T) {
-	// Test file location
-	fpath := "./test.tmp"
-	// Create test file
-	f, err := os.Create(fpath)
-	if err != nil {
-		t.Error(err)
-		return
+func TestSetVersion1(t *testing.T) {
+	var out bytes.Buffer
+	exitCode := -1
+	p := NewParser("progname", "")
+	p.SetOutput(&out)
+	p.ExitFunc = func(code int) { exitCode = code }
+	p.SetVersion("1.2.3")
+	p.String("n", "name", &Options{Required: true})
+	p.NewCommand("run", "")
+
+	err := p.Parse([]string{"progname", "run", "-V"})
+	if err != ErrVersionRequested || exitCode != 0 || out.String() != "1.2.3\n" {
+		t.Errorf("Test %s expected version, got %q, exit code %d and %v", t.Name(), out.String(), exitCode, err)
 	}
-	f.Close()
-	defer os.Remove(fpath)
 
-	testArgs := []string{"progname"}
+	usage := p.Usage(nil)
+	if !strings.Contains(usage, "-V  --version  Print version information") {
+		t.Errorf("Test %s expected version in usage, got:\n%s", t.Name(), usage)
+	}
 
-	p := NewParser("", "")
+	// Names are configurable and version can be removed
+	p.Reset()
+	out.Reset()
+	p.VersionShort = ""
+	p.VersionLong = "ver"
+	p.DisableHelpExit = true
+	err = p.Parse([]string{"progname", "run", "--ver"})
+	if err != ErrVersionRequested || out.String() != "" {
+		t.Errorf("Test %s expected ErrVersionRequested, got %q and %v", t.Name(), out.String(), err)
+	}
 
-	file1 := p.File("f", "file", os.O_RDWR, 0666, &Options{Default: fpath})
+	p.Reset()
+	p.SetVersion("")
+	err = p.Parse([]string{"progname", "run", "--ver"})
+	if err == nil || err == ErrVersionRequested {
+		t.Errorf("Test %s expected error for removed version, got %v", t.Name(), err)
+	}
+}
 
-	err = p.Parse(testArgs)
+func TestAddNote1(t *testing.T) {
+	p := NewParser("progname", "")
+	p.DisableHelp = true
+	p.AddNote("Input:")
+	p.String("i", "input", &Options{Help: "Input file", Required: true})
+	p.AddNote("Output:")
+	p.String("o", "output", &Options{Help: "Output file"})
+	p.Flag("", "secret", &Options{Hidden: true})
+	p.AddNote("That is all")
+
+	want := `Arguments:
+
+  Input:
+  -i  --input   Input file
+  Output:
+  -o  --output  Output file
+  That is all
+`
+	if usage := p.Usage(nil); !strings.Contains(usage, want) {
+		t.Errorf("Test %s failed, got:\n%s", t.Name(), usage)
+	}
+
+	// Notes are never taken for arguments
+	err := p.Parse([]string{"progname", "-i", "a"})
 	if err != nil {
 		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
-		return
 	}
-	defer file1.Close()
+	if len(p.Arguments()) != 3 {
+		t.Errorf("Test %s expected 3 arguments, got %d", t.Name(), len(p.Arguments()))
+	}
 }
 
-func TestFileDefaultValueFail(t *testing.T) {
-	// Test file location
-	fpath := "./test.tmp"
-	// Create test file
-	f, err := os.Create(fpath)
-	if err != nil {
-		t.Error(err)
-		return
+func TestHelpFunc1(t *testing.T) {
+	var output bytes.Buffer
+
+	p := NewParser("prog", "program description")
+	p.ExitFunc = func(int) {}
+	p.SetOutput(&output)
+	p.HelpFunc = func(cmd *Command) string {
+		names := make([]string, 0)
+		for _, v := range cmd.Arguments() {
+			names = append(names, v.Long)
+		}
+		for _, v := range cmd.Commands() {
+			names = append(names, v.Name()+":"+v.Description())
+		}
+		return "== " + cmd.Name() + " ==\n" + strings.Join(names, " ") + "\n"
 	}
-	f.Close()
-	defer os.Remove(fpath)
+	_ = p.Flag("v", "verbose", nil)
+	remote := p.NewCommand("remote", "Manage remotes")
+	_ = remote.String("u", "url", nil)
 
-	testArgs := []string{"progname"}
+	want := "== prog ==\nhelp verbose remote:Manage remotes\n"
+	if usage := p.Usage(nil); usage != want {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, usage)
+	}
 
-	p := NewParser("", "")
+	want = "oops\n== remote ==\nurl\n"
+	if usage := remote.Usage("oops"); usage != want {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s]", t.Name(), want, usage)
+	}
 
-	file1 := p.File("f", "file", os.O_RDWR, 0666, &Options{Default: true})
+	err := p.Parse([]string{"prog", "remote", "--help"})
+	if want = "== remote ==\nurl\n"; err != ErrHelpRequested || output.String() != want {
+		t.Errorf("Test %s failed. Want: [%s], got: [%s] with error [%v]", t.Name(), want, output.String(), err)
+	}
 
-	err = p.Parse(testArgs)
-	if err == nil || err.Error() != "cannot use default type [bool] as type [string]" {
-		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	if usage := remote.DefaultUsage(nil); !strings.HasPrefix(usage, "usage: prog remote") {
+		t.Errorf("Test %s failed. Want built-in usage, got: [%s]", t.Name(), usage)
 	}
-	defer file1.Close()
 }
 
-func TestListDefaultValuePass(t *testing.T) {
-	testArgs := []string{"progname"}
-	testList := []string{"test", "list"}
-
-	p := NewParser("progname", "Prog description")
+func TestNegatableList1(t *testing.T) {
+	testArgs := []string{"progname", "--include", "a", "--no-include", "--include", "b", "--no-port", "--no-label", "--label", "k=v"}
 
-	s := p.List("s", "string", &Options{Default: testList})
+	p := NewParser("", "description")
+	include := p.List("I", "include", &Options{Negatable: true})
+	ports := p.IntList("", "port", &Options{Negatable: true, Default: []int{80, 443}})
+	labels := p.StringMap("", "label", &Options{Negatable: true})
+	weights := p.FloatList("", "weight", &Options{Negatable: true, Default: []float64{0.5}})
 
 	err := p.Parse(testArgs)
-
-	// Should fail on failure
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if want := []string{"b"}; !reflect.DeepEqual(*include, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *include)
+	}
+	if len(*ports) != 0 {
+		t.Errorf("Test %s failed. Want: [[]], got: [%v]", t.Name(), *ports)
+	}
+	if want := map[string]string{"k": "v"}; !reflect.DeepEqual(*labels, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *labels)
+	}
+	if want := []float64{0.5}; !reflect.DeepEqual(*weights, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *weights)
 	}
 
-	// Should fail if not true
-	if !reflect.DeepEqual(*s, testList) {
-		t.Errorf("expected [%v], got [%v]", testList, *s)
+	p = NewParser("", "description")
+	_ = p.List("I", "include", &Options{Negatable: true})
+	err = p.Parse([]string{"progname", "--no-include=a"})
+	errStr := "[--no-include] does not take a value"
+	if err == nil || err.Error() != errStr {
+		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
 	}
 }
 
-func TestListDefaultValueFail(t *testing.T) {
-	testArgs := []string{"progname"}
-
-	p := NewParser("progname", "Prog description")
+func TestAliases1(t *testing.T) {
+	testArgs := []string{"progname", "--colour=red", "-vC", "--no-cache-dir", "-Ifoo", "--inc", "bar", "--incl=baz"}
 
-	_ = p.List("s", "string", &Options{Default: false})
+	p := NewParser("", "description")
+	p.AllowAbbreviations = true
+	color := p.String("c", "color", &Options{Aliases: []string{"colour"}, Help: "Output color"})
+	verbose := p.Flag("v", "verbose", nil)
+	count := p.FlagCounter("n", "count", &Options{ShortAliases: []string{"C"}})
+	cache := p.Flag("", "cache", &Options{Aliases: []string{"cache-dir"}, Negatable: true, Default: true})
+	include := p.List("i", "include", &Options{ShortAliases: []string{"I"}, Aliases: []string{"incl"}})
 
 	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	if *color != "red" || !*verbose || *count != 1 || *cache {
+		t.Errorf("Test %s failed. Want: [red true 1 false], got: [%s %v %d %v]", t.Name(), *color, *verbose, *count, *cache)
+	}
+	if want := []string{"foo", "bar", "baz"}; !reflect.DeepEqual(*include, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, *include)
+	}
 
-	// Should pass on failure
-	if err == nil || err.Error() != "cannot use default type [bool] as type [[]string]" {
-		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [[]string]", err)
+	usage := p.Usage(nil)
+	for _, want := range []string{"Output color. Alias: --colour", "Aliases: -I, --incl", "[-c|--color \"<value>\"]"} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("Test %s failed. Usage does not contain [%s]:\n%s", t.Name(), want, usage)
+		}
+	}
+	if strings.Contains(usage, "[--colour") {
+		t.Errorf("Test %s failed. Usage lists alias as a name:\n%s", t.Name(), usage)
 	}
 }
 
-func TestSelectorDefaultValuePass(t *testing.T) {
-	testArgs := []string{"progname"}
-	testString := "test list"
+func TestAliases2(t *testing.T) {
+	p := NewParser("", "description")
+	color := p.String("c", "color", nil)
+	_ = p.String("", "tint", &Options{Aliases: []string{"color"}})
+	_ = p.Flag("x", "extra", &Options{ShortAliases: []string{"c"}})
 
-	p := NewParser("progname", "Prog description")
+	if args := p.Arguments(); len(args) != 2 {
+		t.Errorf("Test %s failed. Want arguments with colliding aliases to be ignored, got: [%+v]", t.Name(), args)
+	}
 
-	s := p.Selector("s", "string", []string{"opt1", "opt2"}, &Options{Default: testString})
+	err := p.Parse([]string{"progname", "--color", "red"})
+	if err != nil || *color != "red" {
+		t.Errorf("Test %s failed. Want: [red], got: [%s] with error [%v]", t.Name(), *color, err)
+	}
+}
 
-	err := p.Parse(testArgs)
+func TestInvokedCommand1(t *testing.T) {
+	newParser := func() (*Parser, *Command, *Command, *Command) {
+		p := NewParser("", "description")
+		remote := p.NewCommand("remote", "Manage remotes")
+		add := remote.NewCommand("add", "Add a remote")
+		_ = add.String("u", "url", &Options{Required: true})
+		remove := remote.NewCommand("remove", "Remove a remote")
+		remove.AddAlias("rm")
+		return p, remote, add, remove
+	}
 
-	// Should fail on failure
+	p, remote, add, remove := newParser()
+	if p.Parsed() {
+		t.Errorf("Test %s failed. Parser is parsed before Parse", t.Name())
+	}
+	err := p.Parse([]string{"progname", "remote", "rm"})
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
 	}
-
-	// Should fail if not true
-	if *s != testString {
-		t.Errorf("expected [%v], got [%v]", testString, *s)
+	if !p.Parsed() || !remote.Happened() || !remove.Happened() || add.Happened() {
+		t.Errorf("Test %s failed. Want: [true true true false], got: [%v %v %v %v]", t.Name(), p.Parsed(), remote.Happened(), remove.Happened(), add.Happened())
+	}
+	if p.InvokedCommand() != remove {
+		t.Errorf("Test %s failed. Want invoked command [remove], got: [%s]", t.Name(), p.InvokedCommand().Name())
+	}
+	if want := []string{"remote", "remove"}; !reflect.DeepEqual(p.CommandPath(), want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, p.CommandPath())
 	}
-}
 
-func TestSelectorDefaultValueFail(t *testing.T) {
-	testArgs := []string{"progname"}
+	p.Reset()
+	if p.Parsed() || len(p.CommandPath()) != 0 || p.InvokedCommand() != &p.Command {
+		t.Errorf("Test %s failed. Want nothing invoked after Reset, got: [%v %v]", t.Name(), p.Parsed(), p.CommandPath())
+	}
 
-	p := NewParser("progname", "Prog description")
+	p, _, _, _ = newParser()
+	err = p.Parse([]string{"progname", "remote", "add"})
+	if err == nil || p.Parsed() {
+		t.Errorf("Test %s failed. Want failed Parse to leave Parser not parsed, got: [%v] with error [%v]", t.Name(), p.Parsed(), err)
+	}
+}
 
-	_ = p.Selector("s", "string", []string{"opt1", "opt2"}, &Options{Default: false})
+func TestMessages1(t *testing.T) {
+	p := NewParser("", "")
+	p.Messages = map[string]string{
+		"[%s] is required":            "[%s] ist erforderlich",
+		"not enough arguments for %s": "zu wenige Argumente für %s",
+		"%s at argument position %d":  "%s an Position %d",
+	}
+	p.ErrorPositions = true
+	p.Int("n", "num", nil)
+	p.String("s", "str", &Options{Required: true})
+	cmd := p.NewCommand("run", "")
+	cmd.Flag("f", "force", &Options{Required: true})
+
+	err := p.Parse([]string{"progname", "--num"})
+	if err == nil || err.Error() != "zu wenige Argumente für -n|--num an Position 1" {
+		t.Errorf("Test %s expected translated error, got %v", t.Name(), err)
+	}
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("Test %s expected ParseError, got %T", t.Name(), err)
+	}
 
-	err := p.Parse(testArgs)
+	p.Reset()
+	err = p.Parse([]string{"progname", "run", "--str", "x"})
+	if err == nil || err.Error() != "[-f|--force] ist erforderlich" {
+		t.Errorf("Test %s expected translated error, got %v", t.Name(), err)
+	}
 
-	// Should pass on failure
-	if err == nil || err.Error() != "cannot use default type [bool] as type [string]" {
-		t.Errorf("Test %s failed: expected error [%s], got error [%+v]", t.Name(), "cannot use default type [bool] as type [string]", err)
+	// Messages without translation stay in English
+	p.Reset()
+	p.ErrorPositions = false
+	err = p.Parse([]string{"progname", "run", "-f", "--str", "x", "-n", "z"})
+	if err == nil || err.Error() != "[-n|--num] must be an integer, got \"z\"" {
+		t.Errorf("Test %s expected English error, got %v", t.Name(), err)
 	}
 }
 
-func TestFloatSimple1(t *testing.T) {
-	pi := "3.1415"
-	piVal := 3.1415
-	testArgs := []string{"progname", "--float1", pi}
+func TestMessages2(t *testing.T) {
+	p := NewParser("", "")
+	p.Messages = map[string]string{
+		"[%s] must be a size such as 512, 10MB or 1.5GiB, got %q": "[%s] muss eine Größe wie 512, 10MB oder 1.5GiB sein, nicht %q",
+		"[%s] must be a percentage such as 50%% or 0.5, got %q":   "[%s] muss ein Prozentsatz wie 50%% oder 0.5 sein, nicht %q",
+		"[%s] must be an absolute URL with host, got %q":          "[%s] muss eine absolute URL mit Host sein, nicht %q",
+	}
+	p.Bytes("", "size", nil)
+	p.Percent("", "ratio", nil)
+	p.URL("", "endpoint", nil)
+
+	testCases := []struct {
+		args []string
+		msg  string
+	}{
+		{[]string{"--size", "big"}, `[--size] muss eine Größe wie 512, 10MB oder 1.5GiB sein, nicht "big"`},
+		{[]string{"--ratio", "half"}, `[--ratio] muss ein Prozentsatz wie 50% oder 0.5 sein, nicht "half"`},
+		{[]string{"--endpoint", "/path"}, `[--endpoint] muss eine absolute URL mit Host sein, nicht "/path"`},
+	}
 
-	p := NewParser("", "description")
-	f1 := p.Float("f", "float1", nil)
-	f2 := p.Float("", "float2", nil)
+	for _, tc := range testCases {
+		p.Reset()
+		err := p.Parse(append([]string{"progname"}, tc.args...))
+		if err == nil || err.Error() != tc.msg {
+			t.Errorf("Test %s %v expected [%s], got [%+v]", t.Name(), tc.args, tc.msg, err)
+		}
+		if perr, ok := err.(*ParseError); !ok || perr.Kind != KindBadValue {
+			t.Errorf("Test %s %v expected ParseError of [%s] kind, got [%+v]", t.Name(), tc.args, KindBadValue, err)
+		}
+	}
+}
 
-	err := p.Parse(testArgs)
-	if err != nil {
+func TestValidate1(t *testing.T) {
+	p := NewParser("", "")
+	p.String("s", "str", &Options{Default: "x"})
+	p.Selector("m", "mode", []string{"fast", "slow"}, &Options{Default: "slow"})
+	cmd := p.NewCommand("run", "")
+	cmd.Int("n", "num", &Options{Default: 5, Base: 16})
+	cmd.Flag("c", "color", &Options{Negatable: true})
+	cmd.List("t", "tag", &Options{Negatable: true})
+	if err := p.Validate(); err != nil {
 		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
-		return
 	}
 
-	if f1 == nil {
-		t.Errorf("Test %s failed with float1 being nil pointer", t.Name())
-		return
+	testCases := []struct {
+		define func(p *Parser)
+		err    string
+	}{
+		{func(p *Parser) {
+			p.String("s", "str", nil)
+			p.NewCommand("run", "").Int("s", "size", nil)
+		}, "[-s|--size] is ignored, its name is already used by [-s|--str]"},
+		{func(p *Parser) { p.Flag("ab", "all", nil) }, "[-ab|--all] is ignored, it must have a long name and at most one character short name"},
+		{func(p *Parser) { p.String("s", "str", &Options{Required: true, Default: "x"}) }, "[-s|--str] is required, so its default value is never used"},
+		{func(p *Parser) { p.Int("n", "num", &Options{Default: "5"}) }, "cannot use default type [string] as type [int]"},
+		{func(p *Parser) {
+			p.Selector("m", "mode", []string{"fast", "slow"}, &Options{Default: "medium"})
+		}, "bad default value for [-m|--mode]. Allowed values are [fast slow]"},
+		{func(p *Parser) { p.String("s", "str", &Options{Base: 16}) }, "[-s|--str] of type string cannot have Base"},
+		{func(p *Parser) { p.Int("n", "num", &Options{Negatable: true}) }, "[-n|--num] of type int cannot be Negatable"},
+	}
+	for _, tc := range testCases {
+		p := NewParser("", "")
+		tc.define(p)
+		err := p.Validate()
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("Test %s expected error %q, got %v", t.Name(), tc.err, err)
+		}
 	}
 
-	if f2 == nil {
-		t.Errorf("Test %s failed with flag2 being nil pointer", t.Name())
-		return
+	// Validate does not leave default values behind
+	p = NewParser("", "")
+	n := p.Int("n", "num", &Options{Default: 5})
+	if err := p.Validate(); err != nil || *n != 0 {
+		t.Errorf("Test %s expected value to be kept, got %d and %v", t.Name(), *n, err)
 	}
+}
 
-	if *f1 != piVal {
-		t.Errorf("Test %s failed. Want: [%f], got: [%f]", t.Name(), piVal, *f1)
+func TestAllowFileValue1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "argparse")
+	if err != nil {
+		t.Error(err)
 		return
 	}
-
-	if *f2 != 0 {
-		t.Errorf("Test %s failed. Want: [%f], got: [%f]", t.Name(), 0.0, *f2)
+	defer os.RemoveAll(dir)
+	secret := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(secret, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Error(err)
+		return
+	}
+	count := filepath.Join(dir, "count")
+	if err := ioutil.WriteFile(count, []byte("many"), 0600); err != nil {
+		t.Error(err)
 		return
 	}
-}
-
-func TestFloatFail1(t *testing.T) {
-	badArg := "stringNotANumber"
-	testArgs := []string{"progname", "--float1", badArg}
 
-	p := NewParser("", "description")
-	f1 := p.Float("f", "float1", nil)
+	p := NewParser("", "")
+	token := p.String("t", "token", &Options{AllowFileValue: true})
+	user := p.String("u", "user", &Options{AllowFileValue: true})
+	p.Int("n", "num", &Options{AllowFileValue: true})
+	plain := p.String("p", "plain", nil)
 
-	err := p.Parse(testArgs)
-	errStr := "[-f|--float1] bad floating point value [stringNotANumber]"
-	if err == nil || err.Error() != errStr {
-		t.Errorf("Test %s expected [%s], got [%+v]", t.Name(), errStr, err)
+	err = p.Parse([]string{"progname", "--token", "@" + secret, "-u", "@@admin", "--plain", "@" + secret})
+	if err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
 		return
 	}
+	if *token != "s3cr3t" || *user != "@admin" || *plain != "@"+secret {
+		t.Errorf("Test %s got token %q, user %q and plain %q", t.Name(), *token, *user, *plain)
+	}
 
-	if f1 == nil {
-		t.Errorf("Test %s failed with float1 being nil pointer", t.Name())
-		return
+	p.Reset()
+	missing := filepath.Join(dir, "missing")
+	err = p.Parse([]string{"progname", "--token", "@" + missing})
+	if err == nil || err.Error() != fmt.Sprintf("[-t|--token] value file %q does not exist", missing) {
+		t.Errorf("Test %s expected missing file error, got %v", t.Name(), err)
 	}
 
-	if *f1 != 0 {
-		t.Errorf("Test %s failed. Want: [0], got: [%f]", t.Name(), *f1)
-		return
+	p.Reset()
+	err = p.Parse([]string{"progname", "-n", "@" + count})
+	if err == nil || err.Error() != "[-n|--num] must be an integer, got \"many\"" {
+		t.Errorf("Test %s expected integer error, got %v", t.Name(), err)
 	}
 }
 
-var pUsageString = `test string
-usage: prog [-h|--help]
-
-            program description
-
-Arguments:
-
-  -h  --help  Print help information
-
-`
-
-func TestUsageString(t *testing.T) {
-	p := NewParser("prog", "program description")
+func TestEnv1(t *testing.T) {
+	os.Setenv("ARGPARSE_TEST_KEY", "abc")
+	defer os.Unsetenv("ARGPARSE_TEST_KEY")
+	os.Unsetenv("ARGPARSE_TEST_REGION")
 
-	p.Parse(os.Args)
+	p := NewParser("progname", "")
+	key := p.Env("ARGPARSE_TEST_KEY", &Options{Required: true, Help: "API key"})
+	region := p.Env("ARGPARSE_TEST_REGION", &Options{Default: "eu"})
 
-	usage := p.Usage("test string")
+	// Name of variable on command line is not the argument
+	err := p.Parse([]string{"progname", "--ARGPARSE_TEST_REGION", "us"})
+	if err == nil || err.Error() != "too many arguments" {
+		t.Errorf("Test %s expected error for CLI value, got %v", t.Name(), err)
+	}
 
-	if usage != pUsageString {
-		t.Errorf("%s", usage)
+	p.Reset()
+	if err := p.Parse([]string{"progname"}); err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+		return
+	}
+	if *key != "abc" || *region != "eu" {
+		t.Errorf("Test %s got key %q and region %q", t.Name(), *key, *region)
 	}
-}
 
-type s string
+	usage := p.Usage(nil)
+	if !strings.Contains(usage, "Environment variables:\n\n  ARGPARSE_TEST_KEY     API key\n") ||
+		strings.Contains(usage, "usage: progname [-h|--help] [") {
+		t.Errorf("Test %s expected environment variables in usage, got:\n%s", t.Name(), usage)
+	}
 
-func (s s) String() string {
-	return string(s)
+	os.Unsetenv("ARGPARSE_TEST_KEY")
+	p.Reset()
+	err = p.Parse([]string{"progname"})
+	if err == nil || err.Error() != "[$ARGPARSE_TEST_KEY] is required" {
+		t.Errorf("Test %s expected required error, got %v", t.Name(), err)
+	}
 }
 
-var pUsageStringer = `stringer message
-usage: prog [-h|--help]
-
-            program description
-
-Arguments:
-
-  -h  --help  Print help information
-
-`
+func TestStrict1(t *testing.T) {
+	newParser := func(strict bool) *Parser {
+		p := NewParser("", "description")
+		p.Strict = strict
+		_ = p.String("n", "name", nil)
+		run := p.NewCommand("run", "Run it")
+		_ = run.List("t", "tag", nil)
+		_ = run.Positional("input", nil)
+		return p
+	}
 
-func TestUsageStringer(t *testing.T) {
-	p := NewParser("prog", "program description")
+	failures := []struct {
+		args   []string
+		strict bool
+		err    string
+	}{
+		{[]string{"progname", "run", "in", "--name", "a", "b"}, true, "[-n|--name] takes one value, but [b] follows it"},
+		{[]string{"progname", "run", "in", "-t", "x", "y", "--name=a"}, true, "[-t|--tag] takes one value, but [y] follows it"},
+		{[]string{"progname", "run", "in", "-t", "x", "y"}, false, "too many arguments"},
+		{[]string{"progname", "run", "in", "y", "-t", "x"}, true, "too many arguments"},
+	}
+	for _, v := range failures {
+		p := newParser(v.strict)
+		err := p.Parse(v.args)
+		if err == nil || err.Error() != v.err {
+			t.Errorf("Test %s failed for %v. Want: [%s], got: [%v]", t.Name(), v.args, v.err, err)
+		}
+		if perr, ok := err.(*ParseError); v.strict && v.err != "too many arguments" && (!ok || perr.Kind != KindBadValue) {
+			t.Errorf("Test %s failed for %v. Want error of kind [%s], got: [%#v]", t.Name(), v.args, KindBadValue, err)
+		}
+	}
 
-	p.Parse(os.Args)
+	p := newParser(true)
+	if err := p.Parse([]string{"progname", "run", "--name", "a", "in"}); err != nil {
+		t.Errorf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+}
 
-	var msg s = "stringer message"
+func TestOccurrences1(t *testing.T) {
+	testArgs := []string{"progname", "run", "-vvx", "--tag", "a", "-v", "-ttb", "--name=n", "--no-color"}
 
-	usage := p.Usage(msg)
+	p := NewParser("", "description")
+	verbose := p.FlagCounter("v", "verbose", nil)
+	extra := p.Flag("x", "extra", nil)
+	color := p.Flag("", "color", &Options{Negatable: true})
+	unset := p.String("", "unset", &Options{Default: "d"})
+	run := p.NewCommand("run", "Run it")
+	tags := run.List("t", "tag", nil)
+	name := run.String("", "name", nil)
 
-	if usage != pUsageStringer {
-		t.Errorf("%s", usage)
+	err := p.Parse(testArgs)
+	if err != nil {
+		t.Fatalf("Test %s failed with error: %s", t.Name(), err.Error())
+	}
+	want := []int{3, 1, 0, 0, 2, 1}
+	got := []int{p.Occurrences(verbose), p.Occurrences(extra), p.Occurrences(color), p.Occurrences(unset), p.Occurrences(tags), run.Occurrences(name)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Test %s failed. Want: [%v], got: [%v]", t.Name(), want, got)
 	}
 }
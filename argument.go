@@ -1,99 +1,312 @@
 package argparse
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type arg struct {
-	result   interface{} // Pointer to the resulting value
-	opts     *Options    // Options
-	sname    string      // Short name (in Parser will start with "-"
-	lname    string      // Long name (in Parser will start with "--"
-	size     int         // Size defines how many args after match will need to be consumed
-	unique   bool        // Specifies whether flag should be present only ones
-	parsed   bool        // Specifies whether flag has been parsed already
-	fileFlag int         // File mode to open file with
-	filePerm os.FileMode // File permissions to set a file
-	selector *[]string   // Used in Selector type to allow to choose only one from list of options
-	parent   *Command    // Used to get access to specific Command
+	result      interface{}            // Pointer to the resulting value
+	opts        *Options               // Options
+	sname       string                 // Short name (in Parser will start with "-"
+	lname       string                 // Long name (in Parser will start with "--"
+	size        int                    // Size defines how many args after match will need to be consumed
+	unique      bool                   // Specifies whether flag should be present only ones
+	parsed      bool                   // Specifies whether flag has been parsed already
+	fileFlag    int                    // File mode to open file with
+	filePerm    os.FileMode            // File permissions to set a file
+	selector    *[]string              // Used in Selector type to allow to choose only one from list of options
+	choices     map[string]interface{} // Used in Choice type to map selected option to resulting value
+	parent      *Command               // Used to get access to specific Command
+	positional  bool                   // Positional argument has no names on CLI and takes its value by position
+	counter     bool                   // Counter is a flag that counts how many times it was provided
+	requiredIf  *arg                   // Argument that makes this one required when provided
+	levels      []string               // Used in VerbosityLevel type to map number of occurrences to a level
+	occurrences int                    // Number of times argument was provided
+	bytes       bool                   // Used in Bytes type to parse value as human-readable size
+	jsonTarget  interface{}            // Used in JSON type as a pointer that value is unmarshalled into
+	failed      bool                   // Specifies whether argument had an error while parsing
+	layout      string                 // Used in Time type as layout to parse value with
+	warned      bool                   // Specifies whether deprecation warning was written already
+	env         bool                   // Env argument takes its value only from environment variable named by lname
+	percent     bool                   // Used in Percent type to parse value as percentage
+	listFunc    elementFunc            // Used in ListFunc type to turn every value into list element
 }
 
+// elementFunc makes element of ListFunc list from value provided on CLI
+type elementFunc func(string) (interface{}, error)
+
 type help struct{}
 
-func (o *arg) check(argument string) bool {
-	// Shortcut to showing help
-	if argument == "-h" || argument == "--help" {
-		helpText := o.parent.Usage(nil)
-		fmt.Print(helpText)
-		os.Exit(0)
+type version struct{}
+
+func (o *arg) check(argument string) (bool, error) {
+	// Positional and environment arguments are never matched by name
+	if o.positional || o.env {
+		return false, nil
 	}
+	argument = o.canonical(argument)
+
+	// Value may be attached to the name as in "--name=value", only the name part is matched
+	argument, _, hasValue := o.parent.splitEquals(argument)
 
 	// Check for long name only if not empty
 	if o.lname != "" {
-		// If argument begins with "--" and next is not "-" then it is a long name
-		if len(argument) > 2 && strings.HasPrefix(argument, "--") && argument[2] != '-' {
-			if argument[2:] == o.lname {
-				return true
+		// If argument begins with long prefix and is not followed by another prefix then it is a long name
+		if name, ok := o.parent.longName(argument); ok {
+			matched, err := o.matchLong(name)
+			if err != nil || matched {
+				return matched, err
 			}
 		}
 	}
+	// Check for negated long name
+	if o.negated(argument) {
+		return true, nil
+	}
 	// Check for short name only if not empty
 	if o.sname != "" {
-		// If argument begins with "-" and next is not "-" then it is a short name
-		if len(argument) > 1 && strings.HasPrefix(argument, "-") && argument[1] != '-' {
-			switch o.result.(type) {
-			case *bool:
+		// If argument begins with short prefix and is not followed by another prefix then it is a short name
+		if names, ok := o.parent.shortNames(argument); ok {
+			if len(names) > 1 && strings.Contains(names, o.sname) {
+				if err := o.parent.checkCluster(names); err != nil {
+					return false, err
+				}
+			}
+			if o.stackable() {
+				// Combined shorthand never starts with a name of argument that takes a value,
+				// as the rest of it is the value of that argument
+				if a := o.parent.findShort(names[:1]); a != nil && !a.stackable() {
+					return false, nil
+				}
+				// Value could not tell which of combined flags it belongs to
+				if hasValue && len(names) > 1 && strings.Contains(names, o.sname) {
+					return false, o.newError(KindBadValue, "[%s] combined flags cannot take a value", argument)
+				}
 				// For flags we allow multiple shorthand in one
-				if strings.Contains(argument[1:], o.sname) {
-					return true
+				if strings.Contains(names, o.sname) {
+					return true, nil
 				}
-			default:
+			} else {
 				// For all other types it must be separate argument
-				if argument[1:] == o.sname {
-					return true
+				if names == o.sname {
+					return true, nil
+				}
+				// or have value attached right after the name
+				if o.size > 1 && strings.HasPrefix(names, o.sname) {
+					return true, nil
+				}
+				// or end combined shorthand flags, taking value from the next argument as in "-xvf file"
+				if o.size > 1 && o.parent.endsCluster(names, o) {
+					return true, nil
 				}
 			}
 		}
 	}
 
+	return false, nil
+}
+
+// matchLong checks if name is the long name of argument. When Parser allows abbreviations, unique prefix
+// of the long name matches as well, unless name is exactly the long name of another argument
+func (o *arg) matchLong(name string) (bool, error) {
+	if name == o.lname {
+		return true, nil
+	}
+	if p := o.parent.getParser(); p == nil || !p.AllowAbbreviations || !o.hasLongPrefix(name) {
+		return false, nil
+	}
+	matches := o.parent.findLong(name)
+	if len(matches) > 1 {
+		_, long := o.parent.prefixes()
+		names := make([]string, 0, len(matches))
+		for _, v := range matches {
+			names = append(names, long+v.lname)
+		}
+		sort.Strings(names)
+		return false, o.newError(KindUnknownArgument, "ambiguous flag %s%s: could be %s", long, name, strings.Join(names, ", "))
+	}
+	return len(matches) == 1 && matches[0] == o, nil
+}
+
+// longNames returns long name of argument followed by its aliases
+func (o *arg) longNames() []string {
+	result := make([]string, 0, 1)
+	if o.lname != "" {
+		result = append(result, o.lname)
+	}
+	if o.opts != nil {
+		result = append(result, o.opts.Aliases...)
+	}
+	return result
+}
+
+// snames returns short name of argument followed by its short aliases
+func (o *arg) snames() []string {
+	result := make([]string, 0, 1)
+	if o.sname != "" {
+		result = append(result, o.sname)
+	}
+	if o.opts != nil {
+		result = append(result, o.opts.ShortAliases...)
+	}
+	return result
+}
+
+// hasLong checks if name is the long name of argument or one of its aliases
+func (o *arg) hasLong(name string) bool {
+	for _, v := range o.longNames() {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasShort checks if name is the short name of argument or one of its short aliases
+func (o *arg) hasShort(name string) bool {
+	for _, v := range o.snames() {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLongPrefix checks if long name of argument or one of its aliases starts with name
+func (o *arg) hasLongPrefix(name string) bool {
+	for _, v := range o.longNames() {
+		if strings.HasPrefix(v, name) {
+			return true
+		}
+	}
 	return false
 }
 
+// canonical returns argument with aliases of this argument replaced by its names, as "--color=red" for
+// "--colour=red" or "-vc" for "-vC", so that aliases are matched same way as names themselves
+func (o *arg) canonical(argument string) string {
+	if o.opts == nil || len(o.opts.Aliases) == 0 && len(o.opts.ShortAliases) == 0 {
+		return argument
+	}
+	short, long := o.parent.prefixes()
+	name, value, hasValue := o.parent.splitEquals(argument)
+	if lname, ok := o.parent.longName(name); ok {
+		for _, alias := range o.opts.Aliases {
+			if lname == alias || lname == "no-"+alias {
+				name = long + strings.Replace(lname, alias, o.lname, 1)
+				if hasValue {
+					return name + "=" + value
+				}
+				return name
+			}
+		}
+	}
+	if names, ok := o.parent.shortNames(argument); ok && o.sname != "" {
+		result := []byte(names)
+		// Only names are replaced, what follows argument that takes a value is the value
+		for i := 0; i < len(names); i++ {
+			a := o.parent.findShort(names[i : i+1])
+			if a == nil {
+				break
+			}
+			if a == o {
+				result[i] = o.sname[0]
+			}
+			if !a.stackable() {
+				break
+			}
+		}
+		return short + string(result)
+	}
+	return argument
+}
+
+// stackable checks if short name of the argument can be combined with others in one argument, as in `rm -rf`
+func (o *arg) stackable() bool {
+	switch o.result.(type) {
+	case *bool, **bool:
+		return true
+	}
+	return o.counter
+}
+
+// stacked returns how many times short name of argument is repeated in argument, as 2 for "-vv",
+// or 1 if argument is not combined shorthand flags
+func (o *arg) stacked(argument string) int {
+	if !o.stackable() || o.sname == "" {
+		return 1
+	}
+	if names, ok := o.parent.shortNames(argument); ok {
+		if n := strings.Count(names, o.sname); n > 1 {
+			return n
+		}
+	}
+	return 1
+}
+
 func (o *arg) reduce(position int, args *[]string) {
-	argument := (*args)[position]
+	argument := o.canonical((*args)[position])
+	// Attached value or negated form do not consume any following arguments
+	if _, ok := o.inlineValue(argument); ok || o.negated(argument) {
+		(*args)[position] = ""
+		return
+	}
 	// Check for long name only if not empty
 	if o.lname != "" {
-		// If argument begins with "--" and next is not "-" then it is a long name
-		if len(argument) > 2 && strings.HasPrefix(argument, "--") && argument[2] != '-' {
-			if argument[2:] == o.lname {
+		// If argument begins with long prefix and is not followed by another prefix then it is a long name
+		if name, ok := o.parent.longName(argument); ok {
+			if matched, _ := o.matchLong(name); matched {
 				for i := position; i < position+o.size; i++ {
 					(*args)[i] = ""
 				}
+				return
 			}
 		}
 	}
 	// Check for short name only if not empty
 	if o.sname != "" {
-		// If argument begins with "-" and next is not "-" then it is a short name
-		if len(argument) > 1 && strings.HasPrefix(argument, "-") && argument[1] != '-' {
-			switch o.result.(type) {
-			case *bool:
-				// For flags we allow multiple shorthand in one
-				if strings.Contains(argument[1:], o.sname) {
-					(*args)[position] = strings.Replace(argument, o.sname, "", -1)
-					if (*args)[position] == "-" {
-						(*args)[position] = ""
+		// If argument begins with short prefix and is not followed by another prefix then it is a short name
+		if names, ok := o.parent.shortNames(argument); ok {
+			short, _ := o.parent.prefixes()
+			if o.stackable() {
+				// For flags we allow multiple shorthand in one. Counter
+				// removes only one occurrence as every one of them counts
+				if strings.Contains(names, o.sname) {
+					n := -1
+					if o.counter {
+						n = 1
+					}
+					(*args)[position] = ""
+					if names = strings.Replace(names, o.sname, "", n); names != "" {
+						(*args)[position] = short + names
 					}
 				}
-			default:
+			} else {
 				// For all other types it must be separate argument
-				if argument[1:] == o.sname {
+				if names == o.sname {
 					for i := position; i < position+o.size; i++ {
 						(*args)[i] = ""
 					}
+				} else if o.parent.endsCluster(names, o) {
+					// Combined shorthand flags before the name are left to be parsed
+					(*args)[position] = short + strings.TrimSuffix(names, o.sname)
+					for i := position + 1; i < position+o.size; i++ {
+						(*args)[i] = ""
+					}
 				}
 			}
 		}
@@ -103,7 +316,15 @@ func (o *arg) reduce(position int, args *[]string) {
 func (o *arg) parse(args []string) error {
 	// If unique do not allow more than one time
 	if o.unique && o.parsed {
-		return fmt.Errorf("[%s] can only be present once", o.name())
+		return o.newError(KindConflict, "[%s] can only be present once", o.name())
+	}
+
+	if o.opts != nil && o.opts.AllowFileValue {
+		values, err := o.fileValues(args)
+		if err != nil {
+			return err
+		}
+		args = values
 	}
 
 	// If validation function provided -- execute, on error return it immediately
@@ -113,142 +334,1341 @@ func (o *arg) parse(args []string) error {
 			return err
 		}
 	}
+	if o.opts != nil {
+		for _, validate := range o.opts.Validators {
+			err := validate(args)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	switch o.result.(type) {
-	case *help:
-		helpText := o.parent.Usage(nil)
-		fmt.Print(helpText)
-		os.Exit(0)
+	case **help:
+		return o.parent.invoked().printHelp(nil)
+	case **version:
+		return o.parent.getParser().printVersion()
 	case *bool:
-		*o.result.(*bool) = true
+		value := true
+		// Value can only be attached as in "--flag=false"
+		if len(args) > 0 {
+			val, err := strconv.ParseBool(args[0])
+			if err != nil || len(args) > 1 {
+				return o.newError(KindBadValue, "[%s] does not take a value", o.name())
+			}
+			value = val
+		}
+		*o.result.(*bool) = value
+		o.parsed = true
+	case **bool:
+		value := true
+		// Same as for Flag, value can only be attached
+		if len(args) > 0 {
+			val, err := strconv.ParseBool(args[0])
+			if err != nil || len(args) > 1 {
+				return o.newError(KindBadValue, "[%s] does not take a value", o.name())
+			}
+			value = val
+		}
+		*o.result.(**bool) = &value
 		o.parsed = true
 	case *int:
+		if o.counter {
+			if len(args) > 0 {
+				return o.newError(KindBadValue, "[%s] does not take a value", o.name())
+			}
+			*o.result.(*int)++
+			o.parsed = true
+			break
+		}
+		if o.selector != nil {
+			if len(args) < 1 {
+				return o.newError(KindBadValue, "[%s] must be followed by a string", o.name())
+			}
+			if len(args) > 1 {
+				return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+			}
+			i, err := o.matchSelector(args[0])
+			if err != nil {
+				return err
+			}
+			*o.result.(*int) = i
+			o.parsed = true
+			break
+		}
 		if len(args) < 1 {
-			return fmt.Errorf("[%s] must be followed by an integer", o.name())
+			return o.newError(KindBadValue, "[%s] must be followed by an integer", o.name())
 		}
 		if len(args) > 1 {
-			return fmt.Errorf("[%s] followed by too many arguments", o.name())
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
 		}
-		val, err := strconv.Atoi(args[0])
+		val, err := o.parseInt(args[0], strconv.IntSize)
 		if err != nil {
-			return fmt.Errorf("[%s] bad interger value [%s]", o.name(), args[0])
+			return err
+		}
+		if err := o.checkRange(val); err != nil {
+			return err
 		}
-		*o.result.(*int) = val
+		*o.result.(*int) = int(val)
 		o.parsed = true
-	case *float64:
+	case *uint:
 		if len(args) < 1 {
-			return fmt.Errorf("[%s] must be followed by a floating point number", o.name())
+			return o.newError(KindBadValue, "[%s] must be followed by an integer", o.name())
 		}
 		if len(args) > 1 {
-			return fmt.Errorf("[%s] followed by too many arguments", o.name())
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
 		}
-		val, err := strconv.ParseFloat(args[0], 64)
+		if strings.HasPrefix(args[0], "-") {
+			return o.newError(KindBadValue, "[%s] must be a non-negative integer", o.name())
+		}
+		val, err := o.parseUint(args[0], strconv.IntSize)
 		if err != nil {
-			return fmt.Errorf("[%s] bad floating point value [%s]", o.name(), args[0])
+			return err
+		}
+		if err := o.checkRange(val); err != nil {
+			return err
+		}
+		*o.result.(*uint) = uint(val)
+		o.parsed = true
+	case *int64:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by an integer", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		var val int64
+		var err error
+		if o.bytes {
+			val, err = o.parseBytes(args[0])
+			if err != nil {
+				return err
+			}
+		} else {
+			val, err = o.parseInt(args[0], 64)
+			if err != nil {
+				return err
+			}
+		}
+		if err := o.checkRange(val); err != nil {
+			return err
+		}
+		*o.result.(*int64) = val
+		o.parsed = true
+	case *float64:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a floating point number", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		var val float64
+		var err error
+		if o.percent {
+			val, err = o.parsePercent(args[0])
+			if err != nil {
+				return err
+			}
+		} else {
+			val, err = strconv.ParseFloat(args[0], 64)
+			if err != nil {
+				return o.newError(KindBadValue, "[%s] bad floating point value [%s]", o.name(), args[0])
+			}
+		}
+		if err := o.checkRange(val); err != nil {
+			return err
 		}
 		*o.result.(*float64) = val
 		o.parsed = true
+	case *time.Duration:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a duration", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		val, err := time.ParseDuration(args[0])
+		if err != nil {
+			return o.newError(KindBadValue, "[%s] must be a duration such as 300ms or 1.5h", o.name())
+		}
+		*o.result.(*time.Duration) = val
+		o.parsed = true
+	case *time.Time:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a time", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		val, err := time.Parse(o.layout, args[0])
+		if err != nil {
+			return o.newError(KindBadValue, "[%s] must be a time in layout %q, got %q", o.name(), o.layout, args[0])
+		}
+		*o.result.(*time.Time) = val
+		o.parsed = true
+	case *net.IP:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by an IP address", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		val := net.ParseIP(args[0])
+		if val == nil {
+			return o.newError(KindBadValue, "[%s] is not a valid IP address", o.name())
+		}
+		*o.result.(*net.IP) = val
+		o.parsed = true
+	case *net.HardwareAddr:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a MAC address", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		val, err := net.ParseMAC(args[0])
+		if err != nil {
+			return o.newError(KindBadValue, "[%s] is not a valid MAC address, got %q", o.name(), args[0])
+		}
+		*o.result.(*net.HardwareAddr) = val
+		o.parsed = true
+	case *net.IPNet:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a network in CIDR notation", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		_, val, err := net.ParseCIDR(args[0])
+		if err != nil {
+			return o.newError(KindBadValue, "[%s] is not a valid CIDR network, got %q", o.name(), args[0])
+		}
+		*o.result.(*net.IPNet) = *val
+		o.parsed = true
+	case *url.URL:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a URL", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		val, err := o.parseURL(args[0], o.schemes())
+		if err != nil {
+			return err
+		}
+		*o.result.(*url.URL) = *val
+		o.parsed = true
+	case **regexp.Regexp:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a regular expression", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		val, err := regexp.Compile(args[0])
+		if err != nil {
+			return o.newError(KindBadValue, "[%s] %s", o.name(), err.Error())
+		}
+		*o.result.(**regexp.Regexp) = val
+		o.parsed = true
 	case *string:
+		if o.counter {
+			if len(args) > 0 {
+				return o.newError(KindBadValue, "[%s] does not take a value", o.name())
+			}
+			// Level is clamped at the last one, this occurrence is counted once parsed
+			i := o.occurrences + 1
+			if i >= len(o.levels) {
+				i = len(o.levels) - 1
+			}
+			*o.result.(*string) = o.levels[i]
+			o.parsed = true
+			break
+		}
 		if len(args) < 1 {
-			return fmt.Errorf("[%s] must be followed by a string", o.name())
+			return o.newError(KindBadValue, "[%s] must be followed by a string", o.name())
 		}
 		if len(args) > 1 {
-			return fmt.Errorf("[%s] followed by too many arguments", o.name())
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
 		}
+		value := args[0]
 		// Selector case
 		if o.selector != nil {
-			match := false
-			for _, v := range *o.selector {
-				if args[0] == v {
-					match = true
-				}
+			i, err := o.matchSelector(value)
+			if err != nil {
+				return err
 			}
-			if !match {
-				return fmt.Errorf("bad value for [%s]. Allowed values are %v", o.name(), *o.selector)
+			// Store canonical value from the list of options
+			value = (*o.selector)[i]
+		}
+		// JSON case
+		if o.jsonTarget != nil {
+			err := json.Unmarshal([]byte(value), o.jsonTarget)
+			if err != nil {
+				return o.newError(KindBadValue, "[%s] %s", o.name(), err.Error())
 			}
 		}
-		*o.result.(*string) = args[0]
+		*o.result.(*string) = value
+		o.parsed = true
+	case *interface{}:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a string", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		i, err := o.matchSelector(args[0])
+		if err != nil {
+			return err
+		}
+		*o.result.(*interface{}) = o.choices[(*o.selector)[i]]
 		o.parsed = true
 	case *os.File:
 		if len(args) < 1 {
-			return fmt.Errorf("[%s] must be followed by a path to file", o.name())
+			return o.newError(KindBadValue, "[%s] must be followed by a path to file", o.name())
 		}
 		if len(args) > 1 {
-			return fmt.Errorf("[%s] followed by too many arguments", o.name())
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		if args[0] == "-" && o.opts != nil && o.opts.AllowStdStreams {
+			*o.result.(*os.File) = *o.stdStream()
+			o.parsed = true
+			return nil
 		}
-		f, err := os.OpenFile(args[0], o.fileFlag, o.filePerm)
+		f, err := o.openFile(args[0])
 		if err != nil {
 			return err
 		}
 		*o.result.(*os.File) = *f
 		o.parsed = true
+	case *[]os.File:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a path to file", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		f, err := o.openFile(args[0])
+		if err != nil {
+			o.closeFiles()
+			return err
+		}
+		*o.result.(*[]os.File) = append(*o.result.(*[]os.File), *f)
+		o.parsed = true
 	case *[]string:
 		if len(args) < 1 {
-			return fmt.Errorf("[%s] must be followed by a string", o.name())
+			return o.newError(KindBadValue, "[%s] must be followed by a string", o.name())
 		}
 		if len(args) > 1 {
-			return fmt.Errorf("[%s] followed by too many arguments", o.name())
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		values := o.splitValue(args[0])
+		dup, err := o.duplicates(*o.result.(*[]string), values)
+		if err != nil {
+			return err
+		}
+		for i, v := range values {
+			if !dup[i] {
+				*o.result.(*[]string) = append(*o.result.(*[]string), v)
+			}
+		}
+		o.parsed = true
+	case *[]int:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by an integer", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		values := o.splitValue(args[0])
+		ints := make([]int, 0, len(values))
+		for _, v := range values {
+			val, err := o.parseInt(v, strconv.IntSize)
+			if err != nil {
+				return err
+			}
+			if err := o.checkElementRange(val); err != nil {
+				return err
+			}
+			ints = append(ints, int(val))
+		}
+		present := make([]string, 0, len(*o.result.(*[]int)))
+		for _, v := range *o.result.(*[]int) {
+			present = append(present, strconv.Itoa(v))
+		}
+		keys := make([]string, 0, len(ints))
+		for _, v := range ints {
+			keys = append(keys, strconv.Itoa(v))
+		}
+		dup, err := o.duplicates(present, keys)
+		if err != nil {
+			return err
+		}
+		for i, v := range ints {
+			if !dup[i] {
+				*o.result.(*[]int) = append(*o.result.(*[]int), v)
+			}
+		}
+		o.parsed = true
+	case *[]float64:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a floating point number", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		values := o.splitValue(args[0])
+		floats := make([]float64, 0, len(values))
+		for _, v := range values {
+			val, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return o.newError(KindBadValue, "[%s] bad floating point value [%s]", o.name(), v)
+			}
+			if err := o.checkElementRange(val); err != nil {
+				return err
+			}
+			floats = append(floats, val)
+		}
+		present := make([]string, 0, len(*o.result.(*[]float64)))
+		for _, v := range *o.result.(*[]float64) {
+			present = append(present, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		keys := make([]string, 0, len(floats))
+		for _, v := range floats {
+			keys = append(keys, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		dup, err := o.duplicates(present, keys)
+		if err != nil {
+			return err
+		}
+		for i, v := range floats {
+			if !dup[i] {
+				*o.result.(*[]float64) = append(*o.result.(*[]float64), v)
+			}
+		}
+		o.parsed = true
+	case *[]interface{}:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a value", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		values := o.splitValue(args[0])
+		elements := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			val, err := o.listFunc(v)
+			if err != nil {
+				return o.newError(KindBadValue, "[%s] bad value %q: %s", o.name(), v, err.Error())
+			}
+			elements = append(elements, val)
+		}
+		present := make([]string, 0, len(*o.result.(*[]interface{})))
+		for _, v := range *o.result.(*[]interface{}) {
+			present = append(present, fmt.Sprint(v))
+		}
+		keys := make([]string, 0, len(elements))
+		for _, v := range elements {
+			keys = append(keys, fmt.Sprint(v))
+		}
+		dup, err := o.duplicates(present, keys)
+		if err != nil {
+			return err
+		}
+		for i, v := range elements {
+			if !dup[i] {
+				*o.result.(*[]interface{}) = append(*o.result.(*[]interface{}), v)
+			}
+		}
+		o.parsed = true
+	case *map[string]string:
+		if len(args) < 1 {
+			return o.newError(KindBadValue, "[%s] must be followed by a key=value pair", o.name())
+		}
+		if len(args) > 1 {
+			return o.newError(KindBadValue, "[%s] followed by too many arguments", o.name())
+		}
+		result := *o.result.(*map[string]string)
+		pairs := make(map[string]string)
+		for _, v := range o.splitValue(args[0]) {
+			i := strings.Index(v, "=")
+			if i < 1 {
+				return o.newError(KindBadValue, "[%s] must be in key=value form", o.name())
+			}
+			key := v[:i]
+			if o.opts != nil && o.opts.UniqueKeys {
+				_, seen := pairs[key]
+				if _, ok := result[key]; ok || seen {
+					return o.newError(KindConflict, "[%s] key %q is provided more than once", o.name(), key)
+				}
+			}
+			pairs[key] = v[i+1:]
+		}
+		for key, value := range pairs {
+			result[key] = value
 		}
-		*o.result.(*[]string) = append(*o.result.(*[]string), args[0])
 		o.parsed = true
 	default:
 		return fmt.Errorf("unsupported type [%t]", o.result)
 	}
+	o.occurrences++
+	o.warnDeprecated()
 	return nil
 }
 
-func (o *arg) name() string {
-	var name string
-	if o.lname == "" {
-		name = "-" + o.sname
-	} else if o.sname == "" {
-		name = "--" + o.lname
-	} else {
-		name = "-" + o.sname + "|" + "--" + o.lname
+// fileValues replaces values that start with "@" with contents of the file they name, trimmed of surrounding
+// white space. Value starting with "@@" is taken literally with one "@" removed
+func (o *arg) fileValues(args []string) ([]string, error) {
+	result := make([]string, len(args))
+	for i, v := range args {
+		switch {
+		case strings.HasPrefix(v, "@@"):
+			result[i] = v[1:]
+		case strings.HasPrefix(v, "@"):
+			content, err := ioutil.ReadFile(v[1:])
+			if os.IsNotExist(err) {
+				return nil, o.newError(KindBadValue, "[%s] value file %q does not exist", o.name(), v[1:])
+			}
+			if err != nil {
+				return nil, o.newError(KindBadValue, "[%s] cannot read value file %q: %s", o.name(), v[1:], err.Error())
+			}
+			result[i] = strings.TrimSpace(string(content))
+		default:
+			result[i] = v
+		}
+	}
+	return result, nil
+}
+
+// warnDeprecated writes deprecation message of argument to Parser.WarningOutput the first time it is provided
+func (o *arg) warnDeprecated() {
+	if o.opts == nil || o.opts.Deprecated == "" || o.warned {
+		return
+	}
+	o.warned = true
+	var w io.Writer = os.Stderr
+	if p := o.parent.getParser(); p != nil && p.WarningOutput != nil {
+		w = p.WarningOutput
+	}
+	fmt.Fprintf(w, "%s is deprecated, %s\n", o.name(), o.opts.Deprecated)
+}
+
+// parseInt parses integer value in base set by Options.Base, detecting it from prefix by default
+func (o *arg) parseInt(value string, bitSize int) (int64, error) {
+	base := 0
+	if o.opts != nil {
+		base = o.opts.Base
+	}
+	digits, detected := integerBase(value, base)
+	val, err := strconv.ParseInt(digits, detected, bitSize)
+	if err != nil {
+		return 0, o.integerError(value, base, err)
+	}
+	return val, nil
+}
+
+// parseUint is same as parseInt for unsigned integers
+func (o *arg) parseUint(value string, bitSize int) (uint64, error) {
+	base := 0
+	if o.opts != nil {
+		base = o.opts.Base
+	}
+	digits, detected := integerBase(value, base)
+	val, err := strconv.ParseUint(digits, detected, bitSize)
+	if err != nil {
+		return 0, o.integerError(value, base, err)
+	}
+	return val, nil
+}
+
+// integerError describes why value could not be parsed as integer in base
+func (o *arg) integerError(value string, base int, err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return o.newError(KindBadValue, "[%s] integer is out of range, got %q", o.name(), value)
+	}
+	if base != 0 && base != 10 {
+		return o.newError(KindBadValue, "[%s] must be a base %d integer, got %q", o.name(), base, value)
+	}
+	return o.newError(KindBadValue, "[%s] must be an integer, got %q", o.name(), value)
+}
+
+// splitValue splits value of list argument into elements by Separator, if one was set
+func (o *arg) splitValue(value string) []string {
+	if o.opts == nil || o.opts.Separator == "" {
+		return []string{value}
+	}
+	values := make([]string, 0)
+	for _, v := range strings.Split(value, o.opts.Separator) {
+		if v == "" && o.opts.SkipEmpty {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// duplicates marks list values that were already provided, either earlier on CLI or within the same value,
+// when Options.Unique or Options.DropDuplicates is set. Values are compared in their textual form.
+// Unless duplicates are dropped, the first one is an error
+func (o *arg) duplicates(present []string, values []string) ([]bool, error) {
+	result := make([]bool, len(values))
+	if o.opts == nil || !o.opts.Unique && !o.opts.DropDuplicates {
+		return result, nil
+	}
+	seen := make(map[string]bool)
+	for _, v := range present {
+		seen[v] = true
+	}
+	for i, v := range values {
+		if seen[v] {
+			if !o.opts.DropDuplicates {
+				return nil, o.newError(KindConflict, "[%s] value %q is provided more than once", o.name(), v)
+			}
+			result[i] = true
+		}
+		seen[v] = true
+	}
+	return result, nil
+}
+
+// schemes returns URL schemes allowed by Options.Schemes
+func (o *arg) schemes() []string {
+	if o.opts == nil {
+		return nil
+	}
+	return o.opts.Schemes
+}
+
+// reset marks argument as not provided and sets its result to the value it had when argument was created
+func (o *arg) reset() {
+	o.parsed = false
+	o.failed = false
+	o.warned = false
+	o.occurrences = 0
+	switch o.result.(type) {
+	case *string:
+		*o.result.(*string) = ""
+		if o.levels != nil {
+			*o.result.(*string) = o.levels[0]
+		}
+	case *int:
+		*o.result.(*int) = 0
+		if o.selector != nil {
+			*o.result.(*int) = -1
+		}
+	case *[]string:
+		*o.result.(*[]string) = make([]string, 0)
+	case *[]int:
+		*o.result.(*[]int) = make([]int, 0)
+	case *[]float64:
+		*o.result.(*[]float64) = make([]float64, 0)
+	case *[]os.File:
+		*o.result.(*[]os.File) = make([]os.File, 0)
+	case *[]interface{}:
+		*o.result.(*[]interface{}) = make([]interface{}, 0)
+	case *map[string]string:
+		*o.result.(*map[string]string) = make(map[string]string)
+	default:
+		v := reflect.ValueOf(o.result).Elem()
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
+
+// validate checks definition of argument for Parser.Validate
+func (o *arg) validate() error {
+	if o.opts == nil {
+		return nil
+	}
+	if o.opts.Required && o.opts.Default != nil {
+		return fmt.Errorf("[%s] is required, so its default value is never used", o.name())
+	}
+	kind := o.typeName()
+	if o.opts.Base != 0 && kind != "int" && kind != "uint" && kind != "int64" && kind != "int-list" {
+		return fmt.Errorf("[%s] of type %s cannot have Base", o.name(), kind)
+	}
+	if o.opts.MkdirAll && kind != "file" && kind != "file-list" {
+		return fmt.Errorf("[%s] of type %s cannot have MkdirAll", o.name(), kind)
+	}
+	if o.opts.ConsumeRaw && o.opts.RejectFlagLikeValues {
+		return fmt.Errorf("[%s] cannot have both ConsumeRaw and RejectFlagLikeValues", o.name())
+	}
+	if o.opts.AllowStdStreams && kind != "file" {
+		return fmt.Errorf("[%s] of type %s cannot have AllowStdStreams", o.name(), kind)
+	}
+	if len(o.opts.Schemes) > 0 && kind != "url" {
+		return fmt.Errorf("[%s] of type %s cannot have Schemes", o.name(), kind)
+	}
+	if o.opts.Negatable && !o.negatable() {
+		return fmt.Errorf("[%s] of type %s cannot be Negatable", o.name(), kind)
+	}
+	if o.opts.Default == nil {
+		return nil
+	}
+	switch kind {
+	case "selector":
+		if v, ok := o.opts.Default.(string); ok && !o.allowed(v) {
+			return fmt.Errorf("bad default value for [%s]. Allowed values are %v", o.name(), *o.selector)
+		}
+	case "selector-index":
+		if v, ok := o.opts.Default.(int); ok && (v < 0 || v >= len(*o.selector)) {
+			return fmt.Errorf("bad default index %d for [%s]. Allowed values are %v", v, o.name(), *o.selector)
+		}
+	case "file":
+		if _, ok := o.opts.Default.(string); !ok {
+			return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
+		}
+		return nil
+	case "file-list":
+		if _, ok := o.opts.Default.([]string); !ok {
+			return fmt.Errorf("cannot use default type [%T] as type [[]string]", o.opts.Default)
+		}
+		return nil
+	case "json":
+		v, ok := o.opts.Default.(string)
+		if !ok {
+			return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
+		}
+		target := reflect.New(reflect.TypeOf(o.jsonTarget).Elem()).Interface()
+		if err := json.Unmarshal([]byte(v), target); err != nil {
+			return fmt.Errorf("[%s] %s", o.name(), err.Error())
+		}
+		return nil
+	}
+	// Default is assigned the way Parse would do it, and the value argument had is restored afterwards
+	value := reflect.ValueOf(o.result).Elem()
+	saved := reflect.New(value.Type()).Elem()
+	saved.Set(value)
+	parsed := o.parsed
+	o.parsed = false
+	err := o.setDefault()
+	value.Set(saved)
+	o.parsed = parsed
+	return err
+}
+
+// allowed checks if value is one of Selector options
+func (o *arg) allowed(value string) bool {
+	for _, v := range *o.selector {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stdStream returns standard stream that "-" stands for: os.Stdout for files opened write-only, os.Stdin otherwise
+func (o *arg) stdStream() *os.File {
+	if o.fileFlag&(os.O_WRONLY|os.O_RDWR) == os.O_WRONLY {
+		return os.Stdout
+	}
+	return os.Stdin
+}
+
+// openFile opens file of File and FileList arguments, creating its parent directories first if asked to
+func (o *arg) openFile(path string) (*os.File, error) {
+	if o.opts != nil && o.opts.MkdirAll {
+		perm := o.opts.DirPerm
+		if perm == 0 {
+			perm = 0755
+		}
+		if err := os.MkdirAll(filepath.Dir(path), perm); err != nil {
+			return nil, o.newError(KindBadValue, "[%s] cannot create directory for %q: %s", o.name(), path, err.Error())
+		}
+	}
+	return os.OpenFile(path, o.fileFlag, o.filePerm)
+}
+
+// closeFiles closes all files opened so far by FileList argument and drops them from its result,
+// so that no closed file is left there once opening of another one failed
+func (o *arg) closeFiles() {
+	for i := range *o.result.(*[]os.File) {
+		(*o.result.(*[]os.File))[i].Close()
+	}
+	*o.result.(*[]os.File) = make([]os.File, 0)
+}
+
+// matchSelector returns index of the value in the list of allowed options of Selector
+func (o *arg) matchSelector(value string) (int, error) {
+	for i, v := range *o.selector {
+		if value == v {
+			return i, nil
+		}
+	}
+	if o.opts != nil && o.opts.CaseInsensitive {
+		for i, v := range *o.selector {
+			if strings.EqualFold(value, v) {
+				return i, nil
+			}
+		}
+	}
+	if o.opts != nil && o.opts.ErrorFormatter != nil {
+		return -1, o.opts.ErrorFormatter(o.name(), *o.selector)
+	}
+	return -1, o.newError(KindBadValue, "bad value for [%s]. Allowed values are %v", o.name(), *o.selector)
+}
+
+// checkRange validates numeric value against Min and Max options if those are set
+func (o *arg) checkRange(value interface{}) error {
+	below, above := o.outOfRange(value)
+	if below {
+		return o.newError(KindBadValue, "[%s] must be >= %v", o.name(), *o.opts.Min)
+	}
+	if above {
+		return o.newError(KindBadValue, "[%s] must be <= %v", o.name(), *o.opts.Max)
+	}
+	return nil
+}
+
+// checkElementRange validates element of numeric list against Min and Max options if those are set
+func (o *arg) checkElementRange(value interface{}) error {
+	below, above := o.outOfRange(value)
+	if below {
+		return o.newError(KindBadValue, "[%s] value %v is below min %v", o.name(), value, *o.opts.Min)
+	}
+	if above {
+		return o.newError(KindBadValue, "[%s] value %v exceeds max %v", o.name(), value, *o.opts.Max)
+	}
+	return nil
+}
+
+// outOfRange tells whether value, which is int64, uint64 or float64, is below Min or above Max options.
+// Integers are compared with bounds as integers, since float64 cannot tell apart integers above 2^53
+func (o *arg) outOfRange(value interface{}) (below, above bool) {
+	if o.opts == nil {
+		return false, false
+	}
+	min, max := o.opts.Min, o.opts.Max
+	switch v := value.(type) {
+	case int64:
+		below = min != nil && !intAtLeast(v, *min)
+		above = max != nil && !intAtMost(v, *max)
+	case uint64:
+		below = min != nil && !uintAtLeast(v, *min)
+		above = max != nil && !uintAtMost(v, *max)
+	case float64:
+		below = min != nil && v < *min
+		above = max != nil && v > *max
+	}
+	return below, above
+}
+
+// intAtLeast tells whether value is not below bound
+func intAtLeast(value int64, bound float64) bool {
+	bound = math.Ceil(bound)
+	if bound >= 1<<63 {
+		return false
+	}
+	if bound < -(1 << 63) {
+		return true
+	}
+	return value >= int64(bound)
+}
+
+// intAtMost tells whether value is not above bound
+func intAtMost(value int64, bound float64) bool {
+	bound = math.Floor(bound)
+	if bound >= 1<<63 {
+		return true
+	}
+	if bound < -(1 << 63) {
+		return false
+	}
+	return value <= int64(bound)
+}
+
+// uintAtLeast tells whether value is not below bound
+func uintAtLeast(value uint64, bound float64) bool {
+	bound = math.Ceil(bound)
+	if bound <= 0 {
+		return true
+	}
+	if bound >= 1<<64 {
+		return false
+	}
+	return value >= uint64(bound)
+}
+
+// uintAtMost tells whether value is not above bound
+func uintAtMost(value uint64, bound float64) bool {
+	bound = math.Floor(bound)
+	if bound < 0 {
+		return false
+	}
+	if bound >= 1<<64 {
+		return true
+	}
+	return value <= uint64(bound)
+}
+
+// negated checks if argument is the "--no-<name>" form of a negatable argument, with long prefix of Parser
+func (o *arg) negated(argument string) bool {
+	if o.opts == nil || !o.opts.Negatable || o.lname == "" {
+		return false
+	}
+	argument, _, _ = o.parent.splitEquals(argument)
+	_, long := o.parent.prefixes()
+	return argument == long+"no-"+o.lname
+}
+
+// negatable checks if argument has a negated form, which flags and lists have
+func (o *arg) negatable() bool {
+	switch o.result.(type) {
+	case *bool, **bool, *[]string, *[]int, *[]float64, *[]interface{}, *map[string]string:
+		return true
+	}
+	return false
+}
+
+// negate is a counterpart of parse for the negated form of argument
+func (o *arg) negate(args []string) error {
+	// If unique do not allow more than one time
+	if o.unique && o.parsed {
+		return o.newError(KindConflict, "[%s] can only be present once", o.name())
+	}
+
+	if len(args) > 0 {
+		_, long := o.parent.prefixes()
+		return o.newError(KindBadValue, "[%sno-%s] does not take a value", long, o.lname)
+	}
+
+	switch o.result.(type) {
+	case *bool:
+		*o.result.(*bool) = false
+		o.parsed = true
+	case **bool:
+		value := false
+		*o.result.(**bool) = &value
+		o.parsed = true
+	// Lists are cleared, so that values following the negated form are added to empty list
+	case *[]string:
+		*o.result.(*[]string) = make([]string, 0)
+		o.parsed = true
+	case *[]int:
+		*o.result.(*[]int) = make([]int, 0)
+		o.parsed = true
+	case *[]float64:
+		*o.result.(*[]float64) = make([]float64, 0)
+		o.parsed = true
+	case *[]interface{}:
+		*o.result.(*[]interface{}) = make([]interface{}, 0)
+		o.parsed = true
+	case *map[string]string:
+		*o.result.(*map[string]string) = make(map[string]string)
+		o.parsed = true
+	default:
+		return o.newError(KindBadValue, "[%s] cannot be negated", o.name())
+	}
+	o.warnDeprecated()
+	return nil
+}
+
+// inlineValue returns value attached to the argument name, as in "--name=value", "-n=value" or "-nvalue".
+// Value right after short name is only possible for arguments that take a value
+func (o *arg) inlineValue(argument string) (string, bool) {
+	if o.sname != "" && o.size > 1 && !o.stackable() {
+		if names, ok := o.parent.shortNames(argument); ok && strings.HasPrefix(names, o.sname) && len(names) > len(o.sname) {
+			return strings.TrimPrefix(names[len(o.sname):], "="), true
+		}
+	}
+	_, value, ok := o.parent.splitEquals(argument)
+	return value, ok
+}
+
+// checkEquals returns error if argument requires its value in "--name=value" form and got it any other way
+func (o *arg) checkEquals(argument string) error {
+	if o.size < 2 || o.opts == nil || !o.opts.RequireEquals {
+		return nil
+	}
+	if _, _, ok := o.parent.splitEquals(argument); ok {
+		return nil
+	}
+	short, long := o.parent.prefixes()
+	name := long + o.lname
+	if o.lname == "" {
+		name = short + o.sname
+	}
+	return o.newError(KindBadValue, "[%s] value must be attached with \"=\", as in %s=<value>", o.name(), name)
+}
+
+// checkFlagLike returns error if argument rejects flag-like values and one of values is a name of another argument
+func (o *arg) checkFlagLike(values []string) error {
+	if o.opts == nil || !o.opts.RejectFlagLikeValues {
+		return nil
+	}
+	for _, v := range values {
+		if o.parent.namesArgument(v) {
+			return o.newError(KindBadValue, "[%s] got value %q that is an argument name, value is probably missing", o.name(), v)
+		}
+	}
+	return nil
+}
+
+func (o *arg) name() string {
+	var name string
+	short, long := o.parent.prefixes()
+	if o.positional {
+		name = o.lname
+	} else if o.env {
+		name = "$" + o.lname
+	} else if o.lname == "" {
+		name = short + o.sname
+	} else if o.sname == "" {
+		name = long + o.lname
+	} else {
+		name = short + o.sname + "|" + long + o.lname
 	}
 	return name
 }
 
+// hidden checks if argument should be left out of Usage output
+func (o *arg) hidden() bool {
+	return o.opts != nil && (o.opts.Hidden || o.opts.Help == DisableDescription)
+}
+
 func (o *arg) usage() string {
 	var result string
 	result = o.name()
+	// Positional and counter arguments only show their names
+	if !o.positional && !o.counter {
+		result = result + o.valueUsage()
+	}
+	if o.positional && o.isList() {
+		result = result + "..."
+	}
+	if o.opts == nil || o.opts.Required == false {
+		result = "[" + result + "]"
+	}
+	if o.opts != nil && o.opts.Default != nil {
+		result = result + " (default: " + o.formatDefault() + ")"
+	}
+	return result
+}
+
+// valueUsage returns description of the value argument takes as shown in usage
+func (o *arg) valueUsage() string {
+	var result string
+	// Metavar replaces description of the value for any argument that takes one
+	if o.size > 1 && o.opts != nil && o.opts.Metavar != "" {
+		result = " " + o.opts.Metavar
+		switch o.result.(type) {
+		case *[]os.File, *[]string, *[]int, *[]float64, *[]interface{}:
+			result = result + " [" + o.name() + " " + o.opts.Metavar + " ...]"
+		}
+		return result
+	}
 	switch o.result.(type) {
-	case *bool:
-		break
+	case *bool, **bool:
+		if o.opts != nil && o.opts.Negatable {
+			_, long := o.parent.prefixes()
+			result = "|" + long + "no-" + o.lname
+		}
 	case *int:
-		result = result + " <integer>"
+		if o.selector != nil {
+			result = " (" + strings.Join(*o.selector, "|") + ")"
+		} else {
+			result = " <integer>"
+		}
+	case *int64:
+		if o.bytes {
+			result = " <size>"
+		} else {
+			result = " <integer>"
+		}
+	case *uint:
+		result = " <integer>"
 	case *float64:
-		result = result + " <float>"
+		if o.percent {
+			result = " <percent>"
+		} else {
+			result = " <float>"
+		}
+	case *time.Duration:
+		result = " <duration>"
+	case *time.Time:
+		result = " <time>"
+	case *net.IP:
+		result = " <ip>"
+	case *net.HardwareAddr:
+		result = " <mac>"
+	case *net.IPNet:
+		result = " <cidr>"
+	case *url.URL:
+		result = " <url>"
+	case **regexp.Regexp:
+		result = " <regexp>"
 	case *string:
 		if o.selector != nil {
-			result = result + " (" + strings.Join(*o.selector, "|") + ")"
+			result = " (" + strings.Join(*o.selector, "|") + ")"
+		} else if o.jsonTarget != nil {
+			result = " <json>"
 		} else {
-			result = result + " \"<value>\""
+			result = " \"<value>\""
 		}
+	case *interface{}:
+		result = " (" + strings.Join(*o.selector, "|") + ")"
 	case *os.File:
-		result = result + " <file>"
+		result = " <file>"
+	case *[]os.File:
+		result = " <file>" + " [" + o.name() + " <file> ...]"
 	case *[]string:
-		result = result + " \"<value>\"" + " [" + result + " \"<value>\" ...]"
+		result = " \"<value>\"" + " [" + o.name() + " \"<value>\" ...]"
+	case *[]int:
+		result = " <integer>" + " [" + o.name() + " <integer> ...]"
+	case *[]float64:
+		result = " <float>" + " [" + o.name() + " <float> ...]"
+	case *[]interface{}:
+		result = " <value>" + " [" + o.name() + " <value> ...]"
+	case *map[string]string:
+		result = " <key=value>"
 	default:
 		break
 	}
-	if o.opts == nil || o.opts.Required == false {
-		result = "[" + result + "]"
-	}
 	return result
 }
 
+// typeName returns short name of argument type, as used in ArgInfo
+func (o *arg) typeName() string {
+	switch o.result.(type) {
+	case **help:
+		return "help"
+	case **version:
+		return "version"
+	case *bool:
+		return "flag"
+	case **bool:
+		return "optional-flag"
+	case *int:
+		if o.counter {
+			return "counter"
+		}
+		if o.selector != nil {
+			return "selector-index"
+		}
+		return "int"
+	case *uint:
+		return "uint"
+	case *int64:
+		if o.bytes {
+			return "bytes"
+		}
+		return "int64"
+	case *float64:
+		if o.percent {
+			return "percent"
+		}
+		return "float"
+	case *time.Duration:
+		return "duration"
+	case *time.Time:
+		return "time"
+	case *net.IP:
+		return "ip"
+	case *net.HardwareAddr:
+		return "mac"
+	case *net.IPNet:
+		return "cidr"
+	case *url.URL:
+		return "url"
+	case **regexp.Regexp:
+		return "regexp"
+	case *string:
+		if o.counter {
+			return "verbosity-level"
+		}
+		if o.selector != nil {
+			return "selector"
+		}
+		if o.jsonTarget != nil {
+			return "json"
+		}
+		return "string"
+	case *interface{}:
+		return "choice"
+	case *os.File:
+		return "file"
+	case *[]os.File:
+		return "file-list"
+	case *[]string:
+		return "list"
+	case *[]int:
+		return "int-list"
+	case *[]float64:
+		return "float-list"
+	case *[]interface{}:
+		return "func-list"
+	case *map[string]string:
+		return "map"
+	}
+	return ""
+}
+
+// formatDefault returns default value of argument formatted for usage output
+func (o *arg) formatDefault() string {
+	switch v := o.opts.Default.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case []int:
+		values := make([]string, 0, len(v))
+		for _, i := range v {
+			values = append(values, strconv.Itoa(i))
+		}
+		return strings.Join(values, ",")
+	case []float64:
+		values := make([]string, 0, len(v))
+		for _, f := range v {
+			values = append(values, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		return strings.Join(values, ",")
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			values = append(values, fmt.Sprint(e))
+		}
+		return strings.Join(values, ",")
+	case map[string]string:
+		values := make([]string, 0, len(v))
+		for key, value := range v {
+			values = append(values, key+"="+value)
+		}
+		sort.Strings(values)
+		return strings.Join(values, ",")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
 func (o *arg) getHelpMessage() string {
 	message := ""
 	if len(o.opts.Help) > 0 {
 		message += o.opts.Help
 		if !o.opts.Required && o.opts.Default != nil {
-			message += fmt.Sprintf(". Default: %v", o.opts.Default)
+			message += ". Default: " + o.formatDefault()
+		}
+	}
+	// Aliases are not listed as names of argument, only noted here
+	short, long := o.parent.prefixes()
+	aliases := make([]string, 0)
+	for _, v := range o.opts.ShortAliases {
+		aliases = append(aliases, short+v)
+	}
+	for _, v := range o.opts.Aliases {
+		aliases = append(aliases, long+v)
+	}
+	if len(aliases) > 0 {
+		if message != "" {
+			message += ". "
+		}
+		if len(aliases) == 1 {
+			message += "Alias: " + aliases[0]
+		} else {
+			message += "Aliases: " + strings.Join(aliases, ", ")
 		}
 	}
 	return message
 }
 
+// postParse is called once argument had a chance to be found on CLI. It falls back to
+// environment variable, checks number of occurrences, Required and assigns Default if
+// argument was not provided
+func (o *arg) postParse() error {
+	if o.opts == nil || o.failed {
+		return nil
+	}
+
+	// Fall back to environment variable if arg was not provided
+	if o.opts.EnvVar != "" && !o.parsed {
+		if value := os.Getenv(o.opts.EnvVar); value != "" {
+			err := o.parse([]string{value})
+			if err != nil {
+				return err
+			}
+			// Value from environment is not on command line, so it comes after all arguments that are
+			o.parent.queueOnParse(o, math.MaxInt32, value)
+		}
+	}
+
+	// Check how many times arg was provided
+	if o.opts.MaxOccurrences > 0 && o.occurrences > o.opts.MaxOccurrences {
+		return o.newError(KindConflict, "[%s] may be specified at most %d times", o.name(), o.opts.MaxOccurrences)
+	}
+	if o.opts.MinOccurrences > 0 && o.occurrences < o.opts.MinOccurrences {
+		return o.newError(KindMissingRequired, "[%s] must be specified at least %d times", o.name(), o.opts.MinOccurrences)
+	}
+
+	// Check if arg is required and not provided
+	if o.opts.Required && !o.parsed {
+		return o.newError(KindMissingRequired, "[%s] is required", o.name())
+	}
+
+	// Check for argument default value and if provided try to type cast and assign
+	if o.opts.Default != nil && !o.parsed {
+		return o.setDefault()
+	}
+
+	return nil
+}
+
+// parsePositional assigns values to positional argument one by one and then runs postParse
+func (o *arg) parsePositional(values []string) error {
+	for _, v := range values {
+		err := o.parse([]string{v})
+		if err != nil {
+			if err := o.fail(err); err != nil {
+				return err
+			}
+		} else {
+			// Positions of positional values are not tracked, so they come after all named arguments
+			o.parent.queueOnParse(o, math.MaxInt32, v)
+		}
+	}
+	err := o.postParse()
+	if err != nil {
+		if err := o.fail(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// named checks if argument is provided on CLI by name
+func (o *arg) named() bool {
+	return !o.positional && !o.env
+}
+
+// consumesRaw checks if argument takes the following argument as its value whatever it looks like
+func (o *arg) consumesRaw() bool {
+	return o.opts != nil && o.opts.ConsumeRaw && o.size == 2
+}
+
+// isList checks if argument is a list that can take several values
+func (o *arg) isList() bool {
+	switch o.result.(type) {
+	case *[]string, *[]int, *[]float64, *[]os.File, *[]interface{}:
+		return true
+	}
+	return false
+}
+
+// fail marks argument as failed to parse and hands the error to its Command, which either
+// records it to carry on parsing or returns it back to stop
+func (o *arg) fail(err error) error {
+	o.failed = true
+	return o.parent.collect(err)
+}
+
+// getExamples returns examples of argument usage, each on separate line aligned with help message
+func (o *arg) getExamples(width int, padding int) string {
+	result := ""
+	if o.opts == nil {
+		return result
+	}
+	for _, v := range o.opts.Examples {
+		result = result + "\n" + strings.Repeat(" ", padding)
+		result = addToLastLine(result, "e.g. "+v, width, padding, true)
+	}
+	return result
+}
+
 func (o *arg) setDefault() error {
 	// Only set default if it was not parsed, and default value was defined
 	if !o.parsed && o.opts != nil && o.opts.Default != nil {
@@ -258,25 +1678,111 @@ func (o *arg) setDefault() error {
 				return fmt.Errorf("cannot use default type [%T] as type [bool]", o.opts.Default)
 			}
 			*o.result.(*bool) = o.opts.Default.(bool)
+		case **bool:
+			if _, ok := o.opts.Default.(bool); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [bool]", o.opts.Default)
+			}
+			value := o.opts.Default.(bool)
+			*o.result.(**bool) = &value
 		case *int:
 			if _, ok := o.opts.Default.(int); !ok {
 				return fmt.Errorf("cannot use default type [%T] as type [int]", o.opts.Default)
 			}
 			*o.result.(*int) = o.opts.Default.(int)
+		case *uint:
+			if _, ok := o.opts.Default.(uint); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [uint]", o.opts.Default)
+			}
+			*o.result.(*uint) = o.opts.Default.(uint)
+		case *int64:
+			if _, ok := o.opts.Default.(int64); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [int64]", o.opts.Default)
+			}
+			*o.result.(*int64) = o.opts.Default.(int64)
 		case *float64:
 			if _, ok := o.opts.Default.(float64); !ok {
 				return fmt.Errorf("cannot use default type [%T] as type [float64]", o.opts.Default)
 			}
 			*o.result.(*float64) = o.opts.Default.(float64)
+		case *time.Duration:
+			if _, ok := o.opts.Default.(time.Duration); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [time.Duration]", o.opts.Default)
+			}
+			*o.result.(*time.Duration) = o.opts.Default.(time.Duration)
+		case *time.Time:
+			if _, ok := o.opts.Default.(time.Time); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [time.Time]", o.opts.Default)
+			}
+			*o.result.(*time.Time) = o.opts.Default.(time.Time)
+		case *net.IP:
+			if _, ok := o.opts.Default.(net.IP); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [net.IP]", o.opts.Default)
+			}
+			*o.result.(*net.IP) = o.opts.Default.(net.IP)
+		case *net.HardwareAddr:
+			if _, ok := o.opts.Default.(net.HardwareAddr); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [net.HardwareAddr]", o.opts.Default)
+			}
+			*o.result.(*net.HardwareAddr) = o.opts.Default.(net.HardwareAddr)
+		case *net.IPNet:
+			// In case of IPNet we should get string as default value
+			v, ok := o.opts.Default.(string)
+			if !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
+			}
+			_, val, err := net.ParseCIDR(v)
+			if err != nil {
+				return fmt.Errorf("[%s] is not a valid CIDR network, got %q", o.name(), v)
+			}
+			*o.result.(*net.IPNet) = *val
+		case *url.URL:
+			// In case of URL we should get string as default value
+			v, ok := o.opts.Default.(string)
+			if !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
+			}
+			val, err := o.parseURL(v, o.schemes())
+			if err != nil {
+				return err
+			}
+			*o.result.(*url.URL) = *val
+		case **regexp.Regexp:
+			// In case of Regexp we should get string as default value
+			v, ok := o.opts.Default.(string)
+			if !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
+			}
+			val, err := regexp.Compile(v)
+			if err != nil {
+				return fmt.Errorf("[%s] %s", o.name(), err.Error())
+			}
+			*o.result.(**regexp.Regexp) = val
 		case *string:
 			if _, ok := o.opts.Default.(string); !ok {
 				return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
 			}
+			if o.jsonTarget != nil {
+				err := json.Unmarshal([]byte(o.opts.Default.(string)), o.jsonTarget)
+				if err != nil {
+					return fmt.Errorf("[%s] %s", o.name(), err.Error())
+				}
+			}
 			*o.result.(*string) = o.opts.Default.(string)
+		case *interface{}:
+			// In case of Choice we should get one of the keys as default value
+			v, ok := o.opts.Default.(string)
+			if !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
+			}
+			value, ok := o.choices[v]
+			if !ok {
+				return fmt.Errorf("bad default value for [%s]. Allowed values are %v", o.name(), *o.selector)
+			}
+			*o.result.(*interface{}) = value
 		case *os.File:
 			// In case of File we should get string as default value
 			if v, ok := o.opts.Default.(string); ok {
-				f, err := os.OpenFile(v, o.fileFlag, o.filePerm)
+				f, err := o.openFile(v)
 				if err != nil {
 					return err
 				}
@@ -284,11 +1790,45 @@ func (o *arg) setDefault() error {
 			} else {
 				return fmt.Errorf("cannot use default type [%T] as type [string]", o.opts.Default)
 			}
+		case *[]os.File:
+			// In case of FileList we should get list of strings as default value
+			if v, ok := o.opts.Default.([]string); ok {
+				for _, path := range v {
+					f, err := o.openFile(path)
+					if err != nil {
+						o.closeFiles()
+						return err
+					}
+					*o.result.(*[]os.File) = append(*o.result.(*[]os.File), *f)
+				}
+			} else {
+				return fmt.Errorf("cannot use default type [%T] as type [[]string]", o.opts.Default)
+			}
 		case *[]string:
 			if _, ok := o.opts.Default.([]string); !ok {
 				return fmt.Errorf("cannot use default type [%T] as type [[]string]", o.opts.Default)
 			}
 			*o.result.(*[]string) = o.opts.Default.([]string)
+		case *[]int:
+			if _, ok := o.opts.Default.([]int); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [[]int]", o.opts.Default)
+			}
+			*o.result.(*[]int) = o.opts.Default.([]int)
+		case *[]float64:
+			if _, ok := o.opts.Default.([]float64); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [[]float64]", o.opts.Default)
+			}
+			*o.result.(*[]float64) = o.opts.Default.([]float64)
+		case *[]interface{}:
+			if _, ok := o.opts.Default.([]interface{}); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [[]interface{}]", o.opts.Default)
+			}
+			*o.result.(*[]interface{}) = o.opts.Default.([]interface{})
+		case *map[string]string:
+			if _, ok := o.opts.Default.(map[string]string); !ok {
+				return fmt.Errorf("cannot use default type [%T] as type [map[string]string]", o.opts.Default)
+			}
+			*o.result.(*map[string]string) = o.opts.Default.(map[string]string)
 		}
 	}
 
@@ -2,42 +2,729 @@ package argparse
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 )
 
-func (o *Command) help() {
+func (o *Parser) help() {
 	result := &help{}
 
-	a := &arg{
+	o.helpArg = &arg{
 		result: &result,
-		sname:  "h",
-		lname:  "help",
+		sname:  o.HelpShort,
+		lname:  o.HelpLong,
 		size:   1,
 		opts:   &Options{Help: "Print help information"},
 		unique: true,
+		parent: &o.Command,
 	}
 
-	o.addArg(a)
+	o.syncHelp()
+}
+
+// syncHelp applies help options of Parser to its help argument, which is kept first among arguments
+// of Parser unless help is disabled
+func (o *Parser) syncHelp() {
+	defer o.syncVersion()
+	index := -1
+	for i, v := range o.args {
+		if v == o.helpArg {
+			index = i
+		}
+	}
+	if o.DisableHelp {
+		if index >= 0 {
+			o.args = append(o.args[:index], o.args[index+1:]...)
+		}
+		return
+	}
+	o.helpArg.sname = o.HelpShort
+	if o.HelpLong != "" {
+		o.helpArg.lname = o.HelpLong
+	}
+	if index < 0 {
+		o.args = append([]*arg{o.helpArg}, o.args...)
+	}
+}
+
+// syncVersion applies version options of Parser to its version argument, which follows help argument
+// while version is set
+func (o *Parser) syncVersion() {
+	index := -1
+	for i, v := range o.args {
+		if v == o.versionArg {
+			index = i
+		}
+	}
+	if o.version == "" {
+		if index >= 0 {
+			o.args = append(o.args[:index], o.args[index+1:]...)
+		}
+		return
+	}
+	if o.versionArg == nil {
+		result := &version{}
+		o.versionArg = &arg{
+			result: &result,
+			size:   1,
+			opts:   &Options{Help: "Print version information"},
+			unique: true,
+			parent: &o.Command,
+		}
+	}
+	o.versionArg.sname = o.VersionShort
+	if o.VersionLong != "" {
+		o.versionArg.lname = o.VersionLong
+	}
+	if index < 0 {
+		position := 0
+		if len(o.args) > 0 && o.args[0] == o.helpArg {
+			position = 1
+		}
+		o.args = append(o.args[:position], append([]*arg{o.versionArg}, o.args[position:]...)...)
+	}
+}
+
+// invoked returns the deepest parsed command under this Command, which is the one that was invoked on CLI.
+// Returns this Command if none of its sub-commands were parsed
+func (o *Command) invoked() *Command {
+	for _, v := range o.commands {
+		if v.parsed {
+			return v.invoked()
+		}
+	}
+	return o
+}
+
+// commandFor returns the deepest command named in args, which lists command names first
+func (o *Command) commandFor(args []string) *Command {
+	current := o
+	for _, v := range args {
+		var next *Command
+		for _, c := range current.commands {
+			if c.matches(v) {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		current = next
+	}
+	return current
+}
+
+// availableArgs returns arguments that can be used with this Command, which are its own arguments and arguments of
+// all preceding commands. If Parser disabled inheritance, then only help is taken from preceding commands
+func (o *Command) availableArgs() []*arg {
+	result := make([]*arg, 0)
+	p := o.getParser()
+	for current := o; current != nil; current = current.parent {
+		for _, v := range current.args {
+			if current == o || p == nil || !p.DisableInheritance || v == p.helpArg || v == p.versionArg {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// notesAfter returns notes of this Command and preceding commands by argument of named list they are shown after.
+// Note that follows argument which is not in the list is shown after the closest one created before it that is,
+// and notes that follow none of them are returned for nil
+func (o *Command) notesAfter(named []*arg) map[*arg][]string {
+	shown := make(map[*arg]bool)
+	for _, v := range named {
+		shown[v] = true
+	}
+	result := make(map[*arg][]string)
+	p := o.getParser()
+	for current := o; current != nil; current = current.parent {
+		if current != o && p != nil && p.DisableInheritance {
+			continue
+		}
+		for _, n := range current.notes {
+			// Argument note follows may be gone, such as help that was disabled afterwards
+			var anchor, last *arg
+			for _, v := range current.args {
+				if shown[v] {
+					last = v
+				}
+				if v == n.after {
+					anchor = last
+					break
+				}
+			}
+			result[anchor] = append(result[anchor], n.text)
+		}
+	}
+	return result
+}
+
+// availableGroups returns argument groups that apply when this Command is invoked, same as availableArgs
+func (o *Command) availableGroups() []*FlagGroup {
+	result := make([]*FlagGroup, 0)
+	p := o.getParser()
+	for current := o; current != nil; current = current.parent {
+		if current == o || p == nil || !p.DisableInheritance {
+			result = append(result, current.groups...)
+		}
+	}
+	return result
+}
+
+// inherited checks if arguments of this Command are only inherited by invoked sub-command,
+// and thus must not be taken from CLI if Parser disabled inheritance
+func (o *Command) inherited() bool {
+	p := o.getParser()
+	if p == nil || !p.DisableInheritance {
+		return false
+	}
+	for _, v := range o.commands {
+		if v.parsed {
+			return true
+		}
+	}
+	return false
+}
+
+// isHelp checks if argument is a request for help, including "--help=command"
+func (o *Command) isHelp(argument string) bool {
+	p := o.getParser()
+	if p == nil || p.DisableHelp {
+		return false
+	}
+	a := p.helpArg
+	short, long := o.prefixes()
+	return argument == long+a.lname || strings.HasPrefix(argument, long+a.lname+"=") ||
+		(a.sname != "" && argument == short+a.sname)
+}
+
+// isVersion checks if argument is a request for version set with SetVersion
+func (o *Command) isVersion(argument string) bool {
+	p := o.getParser()
+	if p == nil || p.version == "" {
+		return false
+	}
+	a := p.versionArg
+	short, long := o.prefixes()
+	return argument == long+a.lname || (a.sname != "" && argument == short+a.sname)
+}
+
+// helpFor returns command that help at position of args is requested for. It is the invoked command, or its
+// sub-command named after help, as in "--help deploy" or "--help=deploy". Name that is not a sub-command
+// is returned as well, so that it can be reported
+func (o *Command) helpFor(args []string, position int) (*Command, string) {
+	cmd := o.invoked()
+	if _, value, ok := o.splitEquals(args[position]); ok {
+		for _, v := range cmd.commands {
+			if v.matches(value) {
+				return v, ""
+			}
+		}
+		return cmd, value
+	}
+	for _, name := range args[position+1:] {
+		if name == "" || len(cmd.commands) == 0 || o.isName(name) {
+			break
+		}
+		var next *Command
+		for _, v := range cmd.commands {
+			if v.matches(name) {
+				next = v
+				break
+			}
+		}
+		if next == nil {
+			return cmd, name
+		}
+		cmd = next
+	}
+	return cmd, ""
 }
 
 func (o *Command) addArg(a *arg) {
-	if a.lname != "" {
-		if a.sname == "" || len(a.sname) == 1 {
-			// Search parents for overlapping commands and fail silently if any
-			current := o
-			for current != nil {
-				if current.args != nil {
-					for _, v := range current.args {
-						if (a.sname != "" && a.sname == v.sname) || a.lname == v.lname {
-							return
-						}
+	if p := o.getParser(); p != nil {
+		p.syncHelp()
+	}
+	if a.opts != nil && a.opts.Min != nil && a.opts.Max != nil && *a.opts.Min > *a.opts.Max {
+		panic(fmt.Sprintf("argparse: [%s] has Min %v greater than Max %v", a.name(), *a.opts.Min, *a.opts.Max))
+	}
+	if a.opts != nil && a.opts.Hidden && a.opts.Required {
+		panic(fmt.Sprintf("argparse: [%s] cannot be both Hidden and Required", a.name()))
+	}
+	if a.opts != nil && a.opts.RequiredIf != nil {
+		a.requiredIf = o.findArgs([]interface{}{a.opts.RequiredIf})[0]
+	}
+	if a.lname == "" || len(a.sname) > 1 {
+		o.ignored = append(o.ignored, fmt.Errorf("[%s] is ignored, it must have a long name and at most one character short name", a.name()))
+		return
+	}
+	// Search parents for overlapping commands and ignore the argument if any, so that Validate can report it
+	current := o
+	for current != nil {
+		if current.args != nil {
+			for _, v := range current.args {
+				if a.positional != v.positional || a.env != v.env {
+					continue
+				}
+				// Aliases may not be the same as any name or alias of another argument
+				for _, name := range a.snames() {
+					if v.hasShort(name) {
+						o.ignore(a, v)
+						return
+					}
+				}
+				for _, name := range a.longNames() {
+					if v.hasLong(name) {
+						o.ignore(a, v)
+						return
 					}
 				}
-				current = current.parent
 			}
-			a.parent = o
-			o.args = append(o.args, a)
+		}
+		current = current.parent
+	}
+	a.parent = o
+	o.args = append(o.args, a)
+}
+
+// ignore records that argument was not added because its name is taken by another one, to be reported by Validate
+func (o *Command) ignore(a *arg, taken *arg) {
+	o.ignored = append(o.ignored, fmt.Errorf("[%s] is ignored, its name is already used by [%s]", a.name(), taken.name()))
+}
+
+// matches checks if provided name is the name of this Command or one of its aliases
+func (o *Command) matches(name string) bool {
+	if o.name == name {
+		return true
+	}
+	for _, v := range o.aliases {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// usageName returns name of this Command followed by its aliases, as shown in the list of commands
+func (o *Command) usageName() string {
+	if len(o.aliases) == 0 {
+		return o.name
+	}
+	return o.name + " (" + strings.Join(o.aliases, ", ") + ")"
+}
+
+// summary returns the first line of Command description, as shown in the list of commands
+func (o *Command) summary() string {
+	if i := strings.Index(o.description, "\n"); i >= 0 {
+		return strings.TrimSpace(o.description[:i])
+	}
+	return o.description
+}
+
+// findShort returns named argument with provided short name from this Command
+// or any of preceding commands, or nil if there is no such argument
+func (o *Command) findShort(sname string) *arg {
+	for current := o; current != nil; current = current.parent {
+		for _, v := range current.args {
+			if v.named() && v.hasShort(sname) {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// namesArgument checks if value is a name of some argument available to the invoked command,
+// as in "--verbose", "--no-color", "--out=file" or "-vx"
+func (o *Command) namesArgument(value string) bool {
+	cmd := o.invoked()
+	value, _, _ = cmd.splitEquals(value)
+	if name, ok := cmd.longName(value); ok {
+		for _, v := range cmd.findLong(name) {
+			if v.hasLong(name) {
+				return true
+			}
+		}
+		for current := cmd; current != nil; current = current.parent {
+			for _, v := range current.args {
+				if v.negated(value) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if names, ok := cmd.shortNames(value); ok {
+		return cmd.findShort(names[:1]) != nil
+	}
+	return false
+}
+
+// checkCluster fails if combined shorthand flags, such as "xvf" of "-xvf", have an argument that takes a value
+// anywhere but at the end. Shorthand that starts with such argument is not checked, as the rest of it is the value
+func (o *Command) checkCluster(names string) error {
+	if first := o.findShort(names[:1]); first == nil || !first.stackable() {
+		return nil
+	}
+	for i := 1; i < len(names)-1; i++ {
+		if a := o.findShort(names[i : i+1]); a != nil && !a.stackable() {
+			short, _ := o.prefixes()
+			return o.newError(KindBadValue, "[%s] takes a value and must be the last of combined flags [%s]", a.name(), short+names)
+		}
+	}
+	return nil
+}
+
+// endsCluster checks if names are combined shorthand flags followed by short name of last, as "xvf" of "-xvf"
+func (o *Command) endsCluster(names string, last *arg) bool {
+	cluster := strings.TrimSuffix(names, last.sname)
+	if cluster == "" || cluster == names {
+		return false
+	}
+	for i := 0; i < len(cluster); i++ {
+		if a := o.findShort(cluster[i : i+1]); a == nil || !a.stackable() {
+			return false
+		}
+	}
+	return true
+}
+
+// rawValue checks if argument at position of args is the value of preceding argument with Options.ConsumeRaw,
+// which is taken as is even if it looks like a name of argument, help request or "--"
+func (o *Command) rawValue(args []string, position int) bool {
+	if position < 1 {
+		return false
+	}
+	argument := args[position-1]
+	if name, ok := o.longName(argument); ok {
+		for _, v := range o.findLong(name) {
+			if matched, _ := v.matchLong(name); matched || v.hasLong(name) {
+				return v.consumesRaw()
+			}
+		}
+		return false
+	}
+	// Value follows combined short names only if the last of them takes it
+	names, ok := o.shortNames(argument)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(names); i++ {
+		a := o.findShort(names[i : i+1])
+		if a == nil {
+			return false
+		}
+		if a.size > 1 {
+			return i == len(names)-1 && a.consumesRaw()
+		}
+	}
+	return false
+}
+
+// prefixes returns prefixes that short and long names of arguments start with on CLI
+func (o *Command) prefixes() (string, string) {
+	if o != nil {
+		if p := o.getParser(); p != nil && p.shortPrefix != "" {
+			return p.shortPrefix, p.longPrefix
+		}
+	}
+	return "-", "--"
+}
+
+// isName checks if argument looks like a name of argument rather than a value
+func (o *Command) isName(argument string) bool {
+	short, long := o.prefixes()
+	if o.isNegativeNumber(argument) {
+		return false
+	}
+	return len(argument) > len(short) && strings.HasPrefix(argument, short) ||
+		len(argument) > len(long) && strings.HasPrefix(argument, long)
+}
+
+// isNegativeNumber checks if argument is a number with short prefix, such as "-5" or "-0.3", which is not a short
+// name of any argument available to the invoked command. Such argument is a value rather than a name
+func (o *Command) isNegativeNumber(argument string) bool {
+	short, _ := o.prefixes()
+	number := strings.TrimPrefix(argument, short)
+	if number == argument || number == "" || (number[0] < '0' || number[0] > '9') && number[0] != '.' {
+		return false
+	}
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return false
+	}
+	return o.invoked().findShort(number[:1]) == nil
+}
+
+// longName returns name in argument that starts with long prefix, such as "name" of "--name".
+// Name is never empty and never starts with another prefix
+func (o *Command) longName(argument string) (string, bool) {
+	short, long := o.prefixes()
+	name := strings.TrimPrefix(argument, long)
+	if name == argument || name == "" || strings.HasPrefix(name, short) || strings.HasPrefix(name, long) {
+		return "", false
+	}
+	return name, true
+}
+
+// shortNames returns names in argument that starts with short prefix, such as "rf" of "-rf". When both
+// prefixes are the same, argument that is a long name of some argument is never taken for short names
+func (o *Command) shortNames(argument string) (string, bool) {
+	short, long := o.prefixes()
+	names := strings.TrimPrefix(argument, short)
+	if names == argument || names == "" || strings.HasPrefix(names, short) || o.isNegativeNumber(argument) {
+		return "", false
+	}
+	if short == long {
+		name, _, _ := o.splitEquals(argument)
+		for _, v := range o.findLong(name[len(long):]) {
+			if v.hasLong(name[len(long):]) {
+				return "", false
+			}
 		}
 	}
+	return names, true
+}
+
+// splitEquals separates argument in form of "--name=value" into its name and value.
+// Value is everything after the first "=", so it can be empty or contain "=" itself.
+func (o *Command) splitEquals(argument string) (string, string, bool) {
+	short, long := o.prefixes()
+	if !strings.HasPrefix(argument, short) && !strings.HasPrefix(argument, long) {
+		return argument, "", false
+	}
+	i := strings.Index(argument, "=")
+	if i < 0 {
+		return argument, "", false
+	}
+	return argument[:i], argument[i+1:], true
+}
+
+// findLong returns named argument with provided long name from this Command or any of preceding
+// commands. If there is no such argument, then all arguments which long names start with it are returned
+func (o *Command) findLong(lname string) []*arg {
+	result := make([]*arg, 0)
+	for current := o; current != nil; current = current.parent {
+		for _, v := range current.args {
+			if !v.named() {
+				continue
+			}
+			if v.hasLong(lname) {
+				return []*arg{v}
+			}
+			if v.hasLongPrefix(lname) {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// findArgs returns arguments of this Command or any of preceding commands by pointers to their results
+func (o *Command) findArgs(results []interface{}) []*arg {
+	args := make([]*arg, 0, len(results))
+	for _, r := range results {
+		var found *arg
+		for current := o; current != nil && found == nil; current = current.parent {
+			for _, v := range current.args {
+				if v.result == r {
+					found = v
+					break
+				}
+			}
+		}
+		if found == nil {
+			panic(fmt.Sprintf("argparse: [%T] is not an argument of [%s] command", r, o.name))
+		}
+		args = append(args, found)
+	}
+	return args
+}
+
+// lookupArg returns argument of this Command or any of its sub-commands by pointer to its result,
+// or nil if there is no such argument
+func (o *Command) lookupArg(result interface{}) *arg {
+	for _, v := range o.args {
+		if v.result == result {
+			return v
+		}
+	}
+	for _, v := range o.commands {
+		if a := v.lookupArg(result); a != nil {
+			return a
+		}
+	}
+	return nil
+}
+
+// checkConstraints validates conditional requirements of arguments and constraints of
+// argument groups of this Command and its parsed sub-commands
+func (o *Command) checkConstraints() error {
+	for _, v := range o.args {
+		if v.requiredIf != nil && v.requiredIf.parsed && !v.parsed {
+			err := o.collect(o.newError(KindMissingRequired, "[%s] is required when [%s] is provided", v.name(), v.requiredIf.name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, g := range o.groups {
+		err := g.check()
+		if err != nil {
+			if err := o.collect(err); err != nil {
+				return err
+			}
+		}
+	}
+	for _, v := range o.commands {
+		if v.parsed {
+			err := v.checkConstraints()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getParser returns Parser this Command belongs to
+func (o *Command) getParser() *Parser {
+	current := o
+	for current.parent != nil {
+		current = current.parent
+	}
+	return current.parser
+}
+
+// usageWidth returns the width Usage output should be wrapped to
+func (o *Command) usageWidth() int {
+	if p := o.getParser(); p != nil && p.width > 0 {
+		return p.width
+	}
+	return terminalWidth()
+}
+
+// errorAt adds position of the argument that caused an error to its message, if Parser was told to.
+// Position is where argument at index of args stands on command line passed to Parse, with program name at 0
+func (o *Command) errorAt(err error, args []string, index int) error {
+	if p := o.getParser(); p == nil || !p.ErrorPositions || err == ErrHelpRequested || err == ErrVersionRequested {
+		return err
+	}
+	position := o.linePosition(args, index)
+	if perr, ok := err.(*ParseError); ok {
+		return &ParseError{Kind: perr.Kind, Code: perr.Code, msg: fmt.Sprintf(o.message("%s at argument position %d"), perr.msg, position)}
+	}
+	return fmt.Errorf(o.message("%s at argument position %d"), err.Error(), position)
+}
+
+// queueOnParse records that argument got its value at position on command line, so that
+// Options.OnParse of the argument is called once all arguments got their values
+func (o *Command) queueOnParse(oarg *arg, position int, value string) {
+	p := o.getParser()
+	if p == nil || oarg.opts == nil || oarg.opts.OnParse == nil {
+		return
+	}
+	p.onParse = append(p.onParse, parseEvent{arg: oarg, value: value, position: position})
+}
+
+// linePosition returns position on command line of argument at index of args, which are what is left of command
+// line once names of invoked commands were taken off its front
+func (o *Command) linePosition(args []string, index int) int {
+	p := o.getParser()
+	if p == nil {
+		return index
+	}
+	return p.size - len(args) + index
+}
+
+// collect records error to be reported once parsing is done, if Parser was told to collect all errors,
+// and returns nil so that parsing carries on. Otherwise error is returned back to stop parsing right away.
+// Help request always stops parsing
+func (o *Command) collect(err error) error {
+	p := o.getParser()
+	if p == nil || !p.CollectErrors || err == ErrHelpRequested || err == ErrVersionRequested {
+		return err
+	}
+	p.errors = append(p.errors, err)
+	return nil
+}
+
+// printHelp prints usage of this Command, preceded by msg if it is not nil, and exits the program with
+// Parser.ExitFunc. If Parser was told not to exit on help, then nothing is printed and ErrHelpRequested is
+// returned instead. It is returned as well when ExitFunc did not terminate the program, so that parsing stops
+func (o *Command) printHelp(msg interface{}) error {
+	p := o.getParser()
+	if p != nil && p.DisableHelpExit {
+		return ErrHelpRequested
+	}
+	var w io.Writer = os.Stdout
+	if p != nil && p.output != nil {
+		w = p.output
+	}
+	fmt.Fprint(w, o.Usage(msg))
+	exit := os.Exit
+	if p != nil && p.ExitFunc != nil {
+		exit = p.ExitFunc
+	}
+	exit(0)
+	return ErrHelpRequested
+}
+
+// printVersion prints version set with SetVersion and exits the program, same as printHelp does for usage
+func (o *Parser) printVersion() error {
+	if o.DisableHelpExit {
+		return ErrVersionRequested
+	}
+	var w io.Writer = os.Stdout
+	if o.output != nil {
+		w = o.output
+	}
+	fmt.Fprintln(w, o.version)
+	exit := os.Exit
+	if o.ExitFunc != nil {
+		exit = o.ExitFunc
+	}
+	exit(0)
+	return ErrVersionRequested
+}
+
+// suggest returns long name of argument that unknown argument is likely a typo of.
+// Only arguments of this Command and its parsed sub-commands are considered, and
+// suggestion is only made when there is exactly one candidate within 2 edits
+func (o *Command) suggest(argument string) string {
+	argument, _, _ = o.splitEquals(argument)
+	name, ok := o.longName(argument)
+	if !ok {
+		return ""
+	}
+	candidates := o.closeNames(name)
+	if len(candidates) != 1 {
+		return ""
+	}
+	_, long := o.prefixes()
+	return long + candidates[0]
+}
+
+func (o *Command) closeNames(name string) []string {
+	result := make([]string, 0)
+	for _, v := range o.args {
+		if !v.named() {
+			continue
+		}
+		if d := levenshtein(name, v.lname); d > 0 && d <= 2 {
+			result = append(result, v.lname)
+		}
+	}
+	for _, v := range o.commands {
+		if v.parsed {
+			result = append(result, v.closeNames(name)...)
+		}
+	}
+	return result
 }
 
 // Will parse provided list of arguments
@@ -58,7 +745,7 @@ func (o *Command) parse(args *[]string) error {
 	if o.name == "" {
 		o.name = (*args)[0]
 	} else {
-		if o.name != (*args)[0] && o.parent != nil {
+		if !o.matches((*args)[0]) && o.parent != nil {
 			return nil
 		}
 	}
@@ -81,36 +768,135 @@ func (o *Command) parse(args *[]string) error {
 		}
 	}
 
+	// Help is shown for the command that was invoked, or for the one named after help. Help and version
+	// are handled before anything else, so that missing or bad arguments do not get in the way
+	for j, v := range *args {
+		if o.rawValue(*args, j) {
+			continue
+		}
+		if o.isHelp(v) {
+			cmd, unknown := o.helpFor(*args, j)
+			if unknown != "" {
+				return cmd.printHelp(fmt.Sprintf(o.message("unknown command [%s]"), unknown))
+			}
+			return cmd.printHelp(nil)
+		}
+		if o.isVersion(v) {
+			return o.getParser().printVersion()
+		}
+	}
+
 	// Iterate over the args
+	inherited := o.inherited()
 	for i := 0; i < len(o.args); i++ {
 		oarg := o.args[i]
+		// Arguments that are not available to invoked sub-command only get their defaults
+		if inherited && oarg != o.getParser().helpArg && oarg != o.getParser().versionArg {
+			err := oarg.setDefault()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		// All occurrences of argument are found in one left-to-right pass, so lists keep order of command line
 		for j := 0; j < len(*args); j++ {
 			arg := (*args)[j]
-			if arg == "" {
+			if arg == "" || o.rawValue(*args, j) {
 				continue
 			}
-			if oarg.check(arg) {
+			matched, err := oarg.check(arg)
+			if err != nil {
+				return err
+			}
+			if matched {
+				arg = oarg.canonical(arg)
+				// Negated form is handled separately and never consumes following arguments
+				if oarg.negated(arg) {
+					var values []string
+					if _, value, ok := o.splitEquals(arg); ok {
+						values = []string{value}
+					}
+					err := oarg.negate(values)
+					if err != nil {
+						if err := oarg.fail(o.errorAt(err, *args, j)); err != nil {
+							return err
+						}
+					}
+					oarg.reduce(j, args)
+					continue
+				}
+				if err := oarg.checkEquals(arg); err != nil {
+					if err := oarg.fail(o.errorAt(err, *args, j)); err != nil {
+						return err
+					}
+					if _, ok := oarg.inlineValue(arg); ok || len(*args) >= j+oarg.size {
+						oarg.reduce(j, args)
+					} else {
+						(*args)[j] = ""
+					}
+					continue
+				}
+				// Value attached to the name takes place of the following arguments
+				if value, ok := oarg.inlineValue(arg); ok {
+					err := oarg.parse([]string{value})
+					if err != nil {
+						if err := oarg.fail(o.errorAt(err, *args, j)); err != nil {
+							return err
+						}
+					} else {
+						o.queueOnParse(oarg, o.linePosition(*args, j), value)
+					}
+					oarg.reduce(j, args)
+					continue
+				}
 				if len(*args) < j+oarg.size {
-					return fmt.Errorf("not enough arguments for %s", oarg.name())
+					err := oarg.fail(o.errorAt(o.newError(KindBadValue, "not enough arguments for %s", oarg.name()), *args, j))
+					if err != nil {
+						return err
+					}
+					(*args)[j] = ""
+					continue
+				}
+				values := (*args)[j+1 : j+oarg.size]
+				err := oarg.checkFlagLike(values)
+				if err == nil {
+					err = oarg.parse(values)
 				}
-				err := oarg.parse((*args)[j+1 : j+oarg.size])
 				if err != nil {
-					return err
+					if err := oarg.fail(o.errorAt(err, *args, j)); err != nil {
+						return err
+					}
+				} else {
+					value := ""
+					if len(values) > 0 {
+						value = values[0]
+					}
+					o.queueOnParse(oarg, o.linePosition(*args, j), value)
+					// Flag repeated in combined shorthand is parsed once, but every occurrence counts
+					if !oarg.counter {
+						oarg.occurrences += oarg.stacked(arg) - 1
+					}
+					if p := o.getParser(); p != nil && p.Strict && len(values) > 0 {
+						p.values[o.linePosition(*args, j+len(values))] = oarg
+					}
 				}
 				oarg.reduce(j, args)
+				// Counter may be repeated in combined shorthand flags, so look at what is left once more
+				if oarg.counter && (*args)[j] != "" {
+					j--
+				}
 				continue
 			}
 		}
 
-		// Check if arg is required and not provided
-		if oarg.opts != nil && oarg.opts.Required && !oarg.parsed {
-			return fmt.Errorf("[%s] is required", oarg.name())
+		// Positional arguments are assigned only once all named arguments were reduced
+		if oarg.positional {
+			continue
 		}
 
-		// Check for argument default value and if provided try to type cast and assign
-		if oarg.opts != nil && oarg.opts.Default != nil && !oarg.parsed {
-			err := oarg.setDefault()
-			if err != nil {
+		err := oarg.postParse()
+		if err != nil {
+			if err := oarg.fail(err); err != nil {
 				return err
 			}
 		}
@@ -120,3 +906,89 @@ func (o *Command) parse(args *[]string) error {
 	o.parsed = true
 	return nil
 }
+
+// parsePositionals assigns arguments left after all named arguments were reduced to positional
+// arguments of this Command and its parsed sub-commands (sub-commands first), left-to-right.
+// Arguments that follow "--" terminator are used only after all other arguments were taken.
+func (o *Command) parsePositionals(args *[]string, rest *[]string) error {
+	for _, v := range o.commands {
+		if v.parsed {
+			err := v.parsePositionals(args, rest)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var list *arg
+	after := make([]*arg, 0)
+	for _, oarg := range o.args {
+		if !oarg.positional || o.inherited() {
+			continue
+		}
+		if list != nil {
+			after = append(after, oarg)
+			continue
+		}
+		if oarg.isList() {
+			list = oarg
+			continue
+		}
+		values := make([]string, 0, 1)
+		if value, ok := o.nextPositional(args, rest); ok {
+			values = append(values, value)
+		}
+		err := oarg.parsePositional(values)
+		if err != nil {
+			return err
+		}
+	}
+	if list == nil {
+		return nil
+	}
+
+	// Positional list takes everything that is left, except for values of positional arguments
+	// created after it, which are taken from the end
+	values := make([]string, 0)
+	for {
+		value, ok := o.nextPositional(args, rest)
+		if !ok {
+			break
+		}
+		values = append(values, value)
+	}
+	n := len(values) - len(after)
+	if n < 0 {
+		n = 0
+	}
+	for i, oarg := range after {
+		var value []string
+		if n+i < len(values) {
+			value = values[n+i : n+i+1]
+		}
+		err := oarg.parsePositional(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return list.parsePositional(values[:n])
+}
+
+// nextPositional takes the first argument that can be a value of positional argument.
+// Arguments that look like argument names are skipped unless they follow "--" terminator
+func (o *Command) nextPositional(args *[]string, rest *[]string) (string, bool) {
+	for i, v := range *args {
+		if v == "" || o.isName(v) {
+			continue
+		}
+		(*args)[i] = ""
+		return v, true
+	}
+	if len(*rest) > 0 {
+		v := (*rest)[0]
+		*rest = (*rest)[1:]
+		return v, true
+	}
+	return "", false
+}
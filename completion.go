@@ -0,0 +1,308 @@
+package argparse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// walkCommands calls fn for this Command and every command under it, parents first.
+// Path holds names of all commands from top level to the current one
+func (o *Command) walkCommands(path []string, fn func(path []string, cmd *Command)) {
+	fn(path, o)
+	for _, v := range o.commands {
+		sub := make([]string, len(path), len(path)+1)
+		copy(sub, path)
+		v.walkCommands(append(sub, v.name), fn)
+	}
+}
+
+// completionArgs returns named arguments available to this Command. Hidden arguments are not included
+func (o *Command) completionArgs() []*arg {
+	result := make([]*arg, 0)
+	for _, v := range o.availableArgs() {
+		if !v.named() || v.hidden() {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// spellings returns all forms the argument can be provided in on CLI
+func (o *arg) spellings() []string {
+	result := make([]string, 0)
+	short, long := o.parent.prefixes()
+	if o.sname != "" {
+		result = append(result, short+o.sname)
+	}
+	result = append(result, long+o.lname)
+	if o.opts != nil && o.opts.Negatable {
+		result = append(result, long+"no-"+o.lname)
+	}
+	return result
+}
+
+// completionFunction returns name of shell function for the program with all
+// characters not allowed in identifiers replaced
+func completionFunction(progName string) string {
+	return "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, progName) + "_completion"
+}
+
+// shellQuote quotes string with single quotes to be used literally in a shell script
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// completionNode is a command as seen by shell completion: its path from program name,
+// sub-commands that can follow it and named arguments that can be used with it
+type completionNode struct {
+	path     []string
+	commands []*Command
+	args     []*arg
+}
+
+// completionTree returns every command of Parser with what can be completed after it, parents first.
+// All shells complete from it, so that they behave the same way
+func (o *Parser) completionTree(progName string) []completionNode {
+	o.syncHelp()
+	result := make([]completionNode, 0)
+	o.walkCommands([]string{progName}, func(path []string, cmd *Command) {
+		node := completionNode{path: path, commands: make([]*Command, 0), args: cmd.completionArgs()}
+		for _, v := range cmd.commands {
+			if v.description != DisableDescription {
+				node.commands = append(node.commands, v)
+			}
+		}
+		result = append(result, node)
+	})
+	return result
+}
+
+// completionPaths returns quoted paths of all sub-commands, used to tell which command is being completed
+func completionPaths(tree []completionNode) []string {
+	result := make([]string, 0)
+	for _, node := range tree {
+		if len(node.path) > 1 {
+			result = append(result, shellQuote(strings.Join(node.path, " ")))
+		}
+	}
+	return result
+}
+
+// helpText returns help message of argument for completion descriptions, or empty string if there is none
+func (o *arg) helpText() string {
+	if o.opts == nil {
+		return ""
+	}
+	return o.opts.Help
+}
+
+// completesFiles checks if value of argument is completed with file names, as for File and FileList
+func (o *arg) completesFiles() bool {
+	switch o.result.(type) {
+	case *os.File, *[]os.File:
+		return true
+	}
+	return false
+}
+
+// BashCompletion returns bash completion script for this Parser. The script completes names of
+// commands and arguments, allowed values of Selector arguments and file names for File and FileList arguments.
+// Program name is the name of executable the completion is registered for.
+// Output can be sourced directly, e.g. `source <(progname --bash-completion)`.
+func (o *Parser) BashCompletion(progName string) string {
+	var buf bytes.Buffer
+	fn := completionFunction(progName)
+	tree := o.completionTree(progName)
+	paths := completionPaths(tree)
+
+	fmt.Fprintf(&buf, "%s() {\n", fn)
+	fmt.Fprintf(&buf, "    local cur prev cmd i\n")
+	fmt.Fprintf(&buf, "    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(&buf, "    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(&buf, "    cmd=%s\n", shellQuote(progName))
+	if len(paths) > 0 {
+		fmt.Fprintf(&buf, "    for ((i=1; i<COMP_CWORD; i++)); do\n")
+		fmt.Fprintf(&buf, "        case \"$cmd ${COMP_WORDS[i]}\" in\n")
+		fmt.Fprintf(&buf, "            %s) cmd=\"$cmd ${COMP_WORDS[i]}\" ;;\n", strings.Join(paths, "|"))
+		fmt.Fprintf(&buf, "        esac\n")
+		fmt.Fprintf(&buf, "    done\n")
+	}
+	fmt.Fprintf(&buf, "    case \"$cmd\" in\n")
+	for _, node := range tree {
+		words := make([]string, 0)
+		for _, v := range node.commands {
+			words = append(words, v.name)
+		}
+		fmt.Fprintf(&buf, "        %s)\n", shellQuote(strings.Join(node.path, " ")))
+		fmt.Fprintf(&buf, "            case \"$prev\" in\n")
+		for _, v := range node.args {
+			words = append(words, v.spellings()...)
+			names := make([]string, 0)
+			for _, name := range v.spellings() {
+				if !v.negated(name) {
+					names = append(names, shellQuote(name))
+				}
+			}
+			if v.selector != nil {
+				fmt.Fprintf(&buf, "                %s) COMPREPLY=($(compgen -W %s -- \"$cur\")); return 0 ;;\n",
+					strings.Join(names, "|"), shellQuote(strings.Join(*v.selector, " ")))
+			} else if v.completesFiles() {
+				fmt.Fprintf(&buf, "                %s) COMPREPLY=($(compgen -f -- \"$cur\")); return 0 ;;\n",
+					strings.Join(names, "|"))
+			} else if v.size > 1 {
+				// Value can be anything, so there is nothing to suggest
+				fmt.Fprintf(&buf, "                %s) return 0 ;;\n", strings.Join(names, "|"))
+			}
+		}
+		fmt.Fprintf(&buf, "            esac\n")
+		fmt.Fprintf(&buf, "            COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(words, " ")))
+		fmt.Fprintf(&buf, "            ;;\n")
+	}
+	fmt.Fprintf(&buf, "    esac\n")
+	fmt.Fprintf(&buf, "}\n")
+	fmt.Fprintf(&buf, "complete -F %s %s\n", fn, shellQuote(progName))
+
+	return buf.String()
+}
+
+// zshEscape escapes text to be used in descriptions of _arguments and _describe
+func zshEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(s)
+}
+
+// ZshCompletion returns zsh completion script for this Parser. It completes same things as BashCompletion
+// and shows help messages of arguments and descriptions of commands next to them.
+// Output can be saved as `_progname` to a directory in $fpath, or sourced directly.
+func (o *Parser) ZshCompletion(progName string) string {
+	var buf bytes.Buffer
+	fn := completionFunction(progName)
+	tree := o.completionTree(progName)
+	paths := completionPaths(tree)
+
+	fmt.Fprintf(&buf, "#compdef %s\n\n", progName)
+	fmt.Fprintf(&buf, "%s() {\n", fn)
+	fmt.Fprintf(&buf, "    local cmd i\n")
+	fmt.Fprintf(&buf, "    local -a commands\n")
+	fmt.Fprintf(&buf, "    cmd=%s\n", shellQuote(progName))
+	if len(paths) > 0 {
+		fmt.Fprintf(&buf, "    for ((i=2; i<CURRENT; i++)); do\n")
+		fmt.Fprintf(&buf, "        case \"$cmd ${words[i]}\" in\n")
+		fmt.Fprintf(&buf, "            %s) cmd=\"$cmd ${words[i]}\" ;;\n", strings.Join(paths, "|"))
+		fmt.Fprintf(&buf, "        esac\n")
+		fmt.Fprintf(&buf, "    done\n")
+	}
+	fmt.Fprintf(&buf, "    case \"$cmd\" in\n")
+	for _, node := range tree {
+		fmt.Fprintf(&buf, "        %s)\n", shellQuote(strings.Join(node.path, " ")))
+		if len(node.commands) > 0 {
+			commands := make([]string, 0)
+			for _, v := range node.commands {
+				commands = append(commands, shellQuote(strings.Replace(v.name, ":", `\:`, -1)+":"+v.summary()))
+			}
+			fmt.Fprintf(&buf, "            commands=(%s)\n", strings.Join(commands, " "))
+			fmt.Fprintf(&buf, "            _describe -t commands command commands\n")
+		}
+		specs := make([]string, 0)
+		for _, v := range node.args {
+			// Value of argument is completed with allowed values, file names or not at all
+			action := ""
+			if v.selector != nil {
+				action = ":value:(" + strings.Join(*v.selector, " ") + ")"
+			} else if v.completesFiles() {
+				action = ":file:_files"
+			} else if v.size > 1 {
+				action = ":value: "
+			}
+			repeat := ""
+			if !v.unique {
+				repeat = "*"
+			}
+			for _, name := range v.spellings() {
+				spec := repeat + name + "[" + zshEscape(v.helpText()) + "]"
+				if !v.negated(name) {
+					spec = spec + action
+				}
+				specs = append(specs, shellQuote(spec))
+			}
+		}
+		if len(specs) > 0 {
+			fmt.Fprintf(&buf, "            _arguments -s : %s\n", strings.Join(specs, " "))
+		}
+		fmt.Fprintf(&buf, "            ;;\n")
+	}
+	fmt.Fprintf(&buf, "    esac\n")
+	fmt.Fprintf(&buf, "}\n\n")
+	fmt.Fprintf(&buf, "compdef %s %s\n", fn, shellQuote(progName))
+
+	return buf.String()
+}
+
+// fishQuote quotes string with single quotes to be used literally in a fish script
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// FishCompletion returns fish completion script for this Parser. It completes same things as BashCompletion
+// and shows help messages of arguments and descriptions of commands next to them. Output can be saved as
+// `progname.fish` to fish completions directory, or sourced directly.
+func (o *Parser) FishCompletion(progName string) string {
+	var buf bytes.Buffer
+	fn := completionFunction(progName)
+	tree := o.completionTree(progName)
+	paths := completionPaths(tree)
+	prog := fishQuote(progName)
+
+	// Function prints path of the command being completed, such as "progname remote"
+	fmt.Fprintf(&buf, "function %s\n", fn)
+	fmt.Fprintf(&buf, "    set -l cmd %s\n", prog)
+	if len(paths) > 0 {
+		quoted := make([]string, 0, len(paths))
+		for _, v := range tree[1:] {
+			quoted = append(quoted, fishQuote(strings.Join(v.path, " ")))
+		}
+		fmt.Fprintf(&buf, "    for word in (commandline -opc)[2..-1]\n")
+		fmt.Fprintf(&buf, "        switch \"$cmd $word\"\n")
+		fmt.Fprintf(&buf, "            case %s\n", strings.Join(quoted, " "))
+		fmt.Fprintf(&buf, "                set cmd \"$cmd $word\"\n")
+		fmt.Fprintf(&buf, "        end\n")
+		fmt.Fprintf(&buf, "    end\n")
+	}
+	fmt.Fprintf(&buf, "    echo $cmd\n")
+	fmt.Fprintf(&buf, "end\n\n")
+	fmt.Fprintf(&buf, "complete -c %s -f\n", prog)
+	for _, node := range tree {
+		condition := fishQuote("test (" + fn + ") = " + fishQuote(strings.Join(node.path, " ")))
+		for _, v := range node.commands {
+			fmt.Fprintf(&buf, "complete -c %s -n %s -a %s -d %s\n", prog, condition, fishQuote(v.name), fishQuote(v.summary()))
+		}
+		for _, v := range node.args {
+			names := ""
+			if v.sname != "" {
+				names = " -s " + fishQuote(v.sname)
+			}
+			names = names + " -l " + fishQuote(v.lname)
+			value := ""
+			if v.selector != nil {
+				value = " -x -a " + fishQuote(strings.Join(*v.selector, " "))
+			} else if v.completesFiles() {
+				value = " -r -F"
+			} else if v.size > 1 {
+				value = " -x"
+			}
+			fmt.Fprintf(&buf, "complete -c %s -n %s%s%s -d %s\n", prog, condition, names, value, fishQuote(v.helpText()))
+			if v.opts != nil && v.opts.Negatable {
+				fmt.Fprintf(&buf, "complete -c %s -n %s -l %s -d %s\n", prog, condition, fishQuote("no-"+v.lname), fishQuote(v.helpText()))
+			}
+		}
+	}
+
+	return buf.String()
+}
@@ -1,14 +1,145 @@
 package argparse
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrHelpRequested is returned by Parser.Parse when help was requested on command line
+// and Parser.DisableHelpExit is set
+var ErrHelpRequested = errors.New("help requested")
+
+// ErrVersionRequested is returned by Parser.Parse when version was requested on command line
+// and Parser.DisableHelpExit is set
+var ErrVersionRequested = errors.New("version requested")
+
+// ErrorKind tells what kind of mistake on command line caused a ParseError
+type ErrorKind int
+
+const (
+	// KindBadValue is an argument followed by a value that is missing, malformed or out of range
+	KindBadValue ErrorKind = iota
+	// KindMissingRequired is a required argument or sub-command that was not provided
+	KindMissingRequired
+	// KindUnknownArgument is an argument that none of the invoked commands define
+	KindUnknownArgument
+	// KindConflict is an argument provided more times than allowed or together with
+	// arguments it cannot be used with
+	KindConflict
+)
+
+// String returns human readable name of the kind
+func (k ErrorKind) String() string {
+	switch k {
+	case KindBadValue:
+		return "bad value"
+	case KindMissingRequired:
+		return "missing required"
+	case KindUnknownArgument:
+		return "unknown argument"
+	case KindConflict:
+		return "conflict"
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// code returns exit code suggested for the kind. Bad value keeps 2, usual for wrong usage, while
+// the others get codes of their own
+func (k ErrorKind) code() int {
+	switch k {
+	case KindMissingRequired:
+		return 3
+	case KindUnknownArgument:
+		return 4
+	case KindConflict:
+		return 5
+	}
+	return 2
+}
+
+// ParseError is returned by Parser.Parse when command line arguments do not match the definitions.
+// Errors that are not ParseError are caused by the definitions themselves, such as default value of
+// a wrong type, errors returned by validation functions are passed on as they are. Use errors.As to get ParseError
+// and exit with its Code, or pick an exit code of your own by its Kind:
+//
+//	var perr *argparse.ParseError
+//	if errors.As(err, &perr) {
+//		os.Exit(perr.Code)
+//	}
+type ParseError struct {
+	// Kind of the mistake on command line
+	Kind ErrorKind
+	// Code is exit code suggested for the Kind: 2 for KindBadValue, 3 for KindMissingRequired,
+	// 4 for KindUnknownArgument and 5 for KindConflict
+	Code int
+
+	msg string
+}
+
+func (e *ParseError) Error() string {
+	return e.msg
+}
+
+// newParseError creates ParseError of given kind with message formatted as fmt.Errorf would do
+func newParseError(kind ErrorKind, format string, a ...interface{}) error {
+	return &ParseError{Kind: kind, Code: kind.code(), msg: fmt.Sprintf(format, a...)}
+}
+
+// message returns translation of format from Parser.Messages, or format itself if there is none
+func (o *Command) message(format string) string {
+	if p := o.getParser(); p != nil {
+		if translated, ok := p.Messages[format]; ok {
+			return translated
+		}
+	}
+	return format
+}
+
+// newError creates ParseError with message translated with Parser.Messages
+func (o *Command) newError(kind ErrorKind, format string, a ...interface{}) error {
+	return newParseError(kind, o.message(format), a...)
+}
+
+// newError creates ParseError with message translated with Parser.Messages of the Command argument belongs to
+func (o *arg) newError(kind ErrorKind, format string, a ...interface{}) error {
+	if o.parent == nil {
+		return newParseError(kind, format, a...)
+	}
+	return o.parent.newError(kind, format, a...)
+}
+
 type subCommandError struct {
 	error
 	cmd *Command
 }
 
 func (e subCommandError) Error() string {
-	return "[sub]Command required"
+	return e.error.Error()
+}
+
+// Unwrap returns ParseError of the missing sub-command
+func (e subCommandError) Unwrap() error {
+	return e.error
 }
 
 func newSubCommandError(cmd *Command) error {
-	return subCommandError{cmd: cmd}
+	return subCommandError{error: cmd.newError(KindMissingRequired, "[sub]Command required"), cmd: cmd}
+}
+
+// parseErrors holds all errors found by Parser.Parse when Parser.CollectErrors is set.
+// Message lists every error on a separate line
+type parseErrors []error
+
+func (e parseErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns all collected errors
+func (e parseErrors) Unwrap() []error {
+	return e
 }
@@ -1,6 +1,29 @@
 package argparse
 
-import "strings"
+import (
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Stay classy
+const defaultWidth = 80
+
+// terminalWidth returns width of the terminal from COLUMNS environment variable
+// if standard output is a terminal, otherwise it returns default width
+func terminalWidth() int {
+	stat, err := os.Stdout.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return defaultWidth
+	}
+	columns, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || columns <= 0 {
+		return defaultWidth
+	}
+	return columns
+}
 
 func getLastLine(input string) string {
 	slice := strings.Split(input, "\n")
@@ -23,3 +46,129 @@ func addToLastLine(base string, add string, width int, padding int, canSplit boo
 	base = base + " " + add
 	return base
 }
+
+// levenshtein returns edit distance between two strings
+func levenshtein(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// byteUnits maps lower-cased size suffixes to number of bytes they stand for
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// parseBytes parses human-readable size, such as 512, 10MB or 1.5GiB, into number of bytes.
+// Suffixes are case-insensitive, fractional number of bytes is rounded to the nearest whole one
+func (o *arg) parseBytes(value string) (int64, error) {
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+	number, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return 0, o.newError(KindBadValue, "[%s] must be a size such as 512, 10MB or 1.5GiB, got %q", o.name(), value)
+	}
+	unit, ok := byteUnits[strings.ToLower(value[i:])]
+	if !ok {
+		return 0, o.newError(KindBadValue, "[%s] has unknown size suffix %q, allowed are B, KB, MB, GB, TB, KiB, MiB, GiB and TiB", o.name(), value[i:])
+	}
+	size := number*unit + 0.5
+	if size >= math.MaxInt64 {
+		return 0, o.newError(KindBadValue, "[%s] size %q is too large", o.name(), value)
+	}
+	return int64(size), nil
+}
+
+// parsePercent parses percentage, such as 50% or 0.5, into fraction between 0 and 1. Number with "%" suffix
+// is divided by 100. Number without suffix is taken as is when it is between 0 and 1, and as percentage when
+// it is above 1 and up to 100, so that "1" is always 100% rather than 1%
+func (o *arg) parsePercent(value string) (float64, error) {
+	number, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || math.IsNaN(number) {
+		return 0, o.newError(KindBadValue, "[%s] must be a percentage such as 50%% or 0.5, got %q", o.name(), value)
+	}
+	if strings.HasSuffix(value, "%") || number > 1 {
+		number = number / 100
+	}
+	if number < 0 || number > 1 {
+		return 0, o.newError(KindBadValue, "[%s] must be a percentage between 0%% and 100%%, got %q", o.name(), value)
+	}
+	return number, nil
+}
+
+// parseURL parses absolute URL that has a host. If schemes are provided, URL must have one of them
+func (o *arg) parseURL(value string, schemes []string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, o.newError(KindBadValue, "[%s] must be a valid URL, got %q", o.name(), value)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, o.newError(KindBadValue, "[%s] must be an absolute URL with host, got %q", o.name(), value)
+	}
+	if len(schemes) == 0 {
+		return u, nil
+	}
+	for _, v := range schemes {
+		if strings.EqualFold(u.Scheme, v) {
+			return u, nil
+		}
+	}
+	return nil, o.newError(KindBadValue, "[%s] must be a URL with scheme %s, got %q", o.name(), strings.Join(schemes, " or "), value)
+}
+
+// integerBase returns value without its base prefix together with the base it should be parsed in.
+// With base 0 the base is detected from "0x", "0o" and "0b" prefixes, anything else is decimal
+func integerBase(value string, base int) (string, int) {
+	if base != 0 {
+		return value, base
+	}
+	sign := ""
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		sign, value = value[:1], value[1:]
+	}
+	// Sign after prefix, as in "0x-1", is left for parsing to reject
+	if len(value) > 2 && value[0] == '0' && value[2] != '-' && value[2] != '+' {
+		switch value[1] {
+		case 'x', 'X':
+			return sign + value[2:], 16
+		case 'o', 'O':
+			return sign + value[2:], 8
+		case 'b', 'B':
+			return sign + value[2:], 2
+		}
+	}
+	return sign + value, 10
+}
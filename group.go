@@ -0,0 +1,68 @@
+package argparse
+
+import "strings"
+
+// FlagGroup is a constraint on how many of its arguments can be provided together.
+// It is checked once all arguments were parsed and is shown in Usage output of the Command it was added to
+type FlagGroup struct {
+	args []*arg
+	min  int // Minimum number of arguments provided
+	max  int // Maximum number of arguments provided, 0 for no limit
+}
+
+// names returns list of names of arguments as shown in error messages
+func names(args []*arg) string {
+	result := make([]string, 0, len(args))
+	for _, v := range args {
+		if !v.named() || v.lname == "" {
+			result = append(result, v.name())
+		} else {
+			_, long := v.parent.prefixes()
+			result = append(result, long+v.lname)
+		}
+	}
+	return "[" + strings.Join(result, " ") + "]"
+}
+
+// newError creates ParseError with message of the Parser that arguments of the group belong to
+func (g *FlagGroup) newError(kind ErrorKind, format string, a ...interface{}) error {
+	if len(g.args) == 0 {
+		return newParseError(kind, format, a...)
+	}
+	return g.args[0].newError(kind, format, a...)
+}
+
+func (g *FlagGroup) check() error {
+	provided := make([]*arg, 0)
+	for _, v := range g.args {
+		if v.parsed {
+			provided = append(provided, v)
+		}
+	}
+	exactly := g.min == 1 && g.max == 1
+	if len(provided) < g.min {
+		if exactly {
+			return g.newError(KindMissingRequired, "exactly one of %s is required", names(g.args))
+		}
+		return g.newError(KindMissingRequired, "one of %s is required", names(g.args))
+	}
+	if g.max > 0 && len(provided) > g.max {
+		if exactly {
+			return g.newError(KindConflict, "only one of %s allowed", names(provided))
+		}
+		return g.newError(KindConflict, "only one of %s may be specified", names(provided))
+	}
+	return nil
+}
+
+// hint describes the constraint for Usage output
+func (g *FlagGroup) hint() string {
+	switch {
+	case g.min == 1 && g.max == 1:
+		return "Exactly one of " + names(g.args) + " is required"
+	case g.max == 1:
+		return "At most one of " + names(g.args) + " may be specified"
+	default:
+		return "At least one of " + names(g.args) + " is required"
+	}
+}
@@ -0,0 +1,111 @@
+package argparse
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// helpSchema is the version of HelpJSON output. It is only changed when fields are removed or change meaning,
+// new fields may be added without changing it
+const helpSchema = 1
+
+type helpDocument struct {
+	Schema int `json:"schema"`
+	helpCommand
+}
+
+type helpCommand struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Aliases     []string         `json:"aliases,omitempty"`
+	Arguments   []helpArgument   `json:"arguments"`
+	Constraints []helpConstraint `json:"constraints,omitempty"`
+	Commands    []helpCommand    `json:"commands,omitempty"`
+}
+
+type helpArgument struct {
+	Short      string   `json:"short,omitempty"`
+	Long       string   `json:"long"`
+	Type       string   `json:"type"`
+	Help       string   `json:"help,omitempty"`
+	Required   bool     `json:"required"`
+	Positional bool     `json:"positional"`
+	Env        bool     `json:"env"`
+	Default    *string  `json:"default,omitempty"`
+	Selector   []string `json:"selector,omitempty"`
+	Group      string   `json:"group,omitempty"`
+}
+
+type helpConstraint struct {
+	Kind      string   `json:"kind"`
+	Arguments []string `json:"arguments"`
+}
+
+// helpTree describes this Command and all of its sub-commands that are not hidden
+func (o *Command) helpTree() helpCommand {
+	result := helpCommand{
+		Name:        o.name,
+		Description: o.description,
+		Aliases:     o.aliases,
+		Arguments:   make([]helpArgument, 0),
+	}
+	for i, v := range o.Arguments() {
+		if v.Hidden {
+			continue
+		}
+		argument := helpArgument{
+			Short:      v.Short,
+			Long:       v.Long,
+			Type:       v.Type,
+			Help:       v.Help,
+			Required:   v.Required,
+			Positional: v.Positional,
+			Env:        v.Env,
+			Selector:   v.Selector,
+			Group:      v.Group,
+		}
+		if v.Default != nil {
+			value := o.args[i].formatDefault()
+			argument.Default = &value
+		}
+		result.Arguments = append(result.Arguments, argument)
+	}
+	for _, g := range o.groups {
+		constraint := helpConstraint{Kind: "at-least-one", Arguments: make([]string, 0, len(g.args))}
+		switch {
+		case g.min == 1 && g.max == 1:
+			constraint.Kind = "exactly-one"
+		case g.max == 1:
+			constraint.Kind = "at-most-one"
+		}
+		for _, v := range g.args {
+			constraint.Arguments = append(constraint.Arguments, v.lname)
+		}
+		result.Constraints = append(result.Constraints, constraint)
+	}
+	for _, v := range o.Commands() {
+		if v.description == DisableDescription {
+			continue
+		}
+		result.Commands = append(result.Commands, v.helpTree())
+	}
+	return result
+}
+
+// HelpJSON returns description of the program as JSON document, for tools that render forms or documentation
+// of the command line. It is built from the same definitions as Usage output: every command with its name,
+// description, aliases, arguments that are not hidden, as described by Arguments, and constraints of groups
+// of arguments, with sub-commands nested in "commands". Defaults are formatted as strings, as in Usage output.
+// Document has "schema" field with version of the format, which only changes when fields are removed or
+// change their meaning. Hidden commands and arguments are left out.
+func (o *Parser) HelpJSON() []byte {
+	o.syncHelp()
+	doc := helpDocument{Schema: helpSchema, helpCommand: o.helpTree()}
+	if doc.Name == "" {
+		doc.Name = filepath.Base(os.Args[0])
+	}
+	// Document holds only strings, booleans and slices of them, so it always can be marshalled
+	result, _ := json.MarshalIndent(doc, "", "  ")
+	return result
+}
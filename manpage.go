@@ -0,0 +1,132 @@
+package argparse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manEscape escapes text to be used in troff source, so that it is rendered literally
+func manEscape(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	s = strings.Replace(s, "-", `\-`, -1)
+	lines := strings.Split(s, "\n")
+	for i, v := range lines {
+		// Lines starting with control characters would be taken for requests
+		if strings.HasPrefix(v, ".") || strings.HasPrefix(v, "'") {
+			lines[i] = `\&` + v
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeManArgs writes tagged paragraph for every argument that is not hidden
+func writeManArgs(buf *bytes.Buffer, args []*arg) {
+	for _, v := range args {
+		if v.hidden() {
+			continue
+		}
+		fmt.Fprintf(buf, ".TP\n")
+		if !v.named() {
+			fmt.Fprintf(buf, "\\fI%s\\fR\n", manEscape(v.name()))
+		} else {
+			names := make([]string, 0)
+			for _, name := range v.spellings() {
+				names = append(names, `\fB`+manEscape(name)+`\fR`)
+			}
+			value := ""
+			if v.size > 1 && !v.counter {
+				value = ` \fI` + manEscape(strings.TrimSpace(v.valueUsage())) + `\fR`
+			}
+			fmt.Fprintf(buf, "%s%s\n", strings.Join(names, ", "), value)
+		}
+		if v.opts != nil && v.opts.Help != "" {
+			fmt.Fprintf(buf, "%s\n", manEscape(v.getHelpMessage()))
+		}
+		if v.opts != nil && len(v.opts.Examples) > 0 {
+			fmt.Fprintf(buf, ".RS\n.nf\n")
+			for _, example := range v.opts.Examples {
+				fmt.Fprintf(buf, "%s\n", manEscape(example))
+			}
+			fmt.Fprintf(buf, ".fi\n.RE\n")
+		}
+	}
+}
+
+// ManPage returns man page in troff format for this Parser. It is built from the same definitions
+// as Usage output: name and description of the program, all arguments that are not hidden with their
+// help messages and examples, and every sub-command with its own arguments. Section is the manual
+// section the page belongs to, which is 1 for most programs.
+// Output can be viewed directly with `man -l`, e.g. `progname --man-page > progname.1 && man -l progname.1`.
+func (o *Parser) ManPage(section int) string {
+	var buf bytes.Buffer
+	o.syncHelp()
+
+	name := o.name
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+
+	fmt.Fprintf(&buf, ".TH %s %d\n", manEscape(strings.ToUpper(name)), section)
+	fmt.Fprintf(&buf, ".SH NAME\n")
+	if o.description != "" {
+		fmt.Fprintf(&buf, "%s \\- %s\n", manEscape(name), manEscape(o.description))
+	} else {
+		fmt.Fprintf(&buf, "%s\n", manEscape(name))
+	}
+
+	fmt.Fprintf(&buf, ".SH SYNOPSIS\n")
+	fmt.Fprintf(&buf, ".B %s\n", manEscape(name))
+	synopsis := make([]string, 0)
+	if len(o.commands) > 0 {
+		synopsis = append(synopsis, "<command>")
+	}
+	for _, positional := range []bool{false, true} {
+		for _, v := range o.args {
+			if v.positional == positional && !v.env && !v.hidden() {
+				synopsis = append(synopsis, v.usage())
+			}
+		}
+	}
+	if len(synopsis) > 0 {
+		fmt.Fprintf(&buf, "%s\n", manEscape(strings.Join(synopsis, " ")))
+	}
+
+	if o.description != "" {
+		fmt.Fprintf(&buf, ".SH DESCRIPTION\n")
+		fmt.Fprintf(&buf, "%s\n", manEscape(o.description))
+	}
+
+	if len(o.args) > 0 {
+		fmt.Fprintf(&buf, ".SH OPTIONS\n")
+		writeManArgs(&buf, o.args)
+	}
+
+	if len(o.commands) > 0 {
+		fmt.Fprintf(&buf, ".SH SUBCOMMANDS\n")
+		o.walkCommands(nil, func(path []string, cmd *Command) {
+			if len(path) == 0 || cmd.description == DisableDescription {
+				return
+			}
+			fmt.Fprintf(&buf, ".TP\n")
+			fmt.Fprintf(&buf, "\\fB%s\\fR\n", manEscape(strings.Join(path, " ")))
+			if cmd.description != "" {
+				fmt.Fprintf(&buf, "%s\n", manEscape(cmd.description))
+			}
+			if len(cmd.args) > 0 {
+				fmt.Fprintf(&buf, ".RS\n")
+				writeManArgs(&buf, cmd.args)
+				fmt.Fprintf(&buf, ".RE\n")
+			}
+		})
+	}
+
+	if o.Epilog != "" {
+		fmt.Fprintf(&buf, ".SH NOTES\n")
+		fmt.Fprintf(&buf, "%s\n", manEscape(o.Epilog))
+	}
+
+	return buf.String()
+}
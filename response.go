@@ -0,0 +1,99 @@
+package argparse
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// maxResponseDepth limits how deep response files may include each other, which also stops include loops
+const maxResponseDepth = 10
+
+// EnableResponseFiles makes Parser replace every argument that starts with prefix, usually '@', with arguments
+// read from the file named by the rest of it, such as "@args.txt". Arguments in the file are separated by
+// whitespace, quotes and backslash work as in shell to keep spaces in values. Response files may refer to other
+// response files. Arguments after "--" terminator are never expanded.
+func (o *Parser) EnableResponseFiles(prefix byte) {
+	o.responsePrefix = prefix
+}
+
+// expandResponseFiles returns args with all response files replaced by their contents
+func (o *Parser) expandResponseFiles(args []string, depth int) ([]string, error) {
+	result := make([]string, 0, len(args))
+	for i, v := range args {
+		if v == "--" {
+			return append(result, args[i:]...), nil
+		}
+		if len(v) < 2 || v[0] != o.responsePrefix {
+			result = append(result, v)
+			continue
+		}
+		if depth >= maxResponseDepth {
+			return nil, o.newError(KindBadValue, "response file [%s] is nested more than %d levels deep", v[1:], maxResponseDepth)
+		}
+		content, err := ioutil.ReadFile(v[1:])
+		if err != nil {
+			return nil, o.newError(KindBadValue, "cannot read response file [%s]: %s", v[1:], err.Error())
+		}
+		tokens, err := splitResponse(string(content))
+		if err != nil {
+			return nil, o.newError(KindBadValue, "response file [%s] %s", v[1:], err.Error())
+		}
+		tokens, err = o.expandResponseFiles(tokens, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tokens...)
+	}
+	return result, nil
+}
+
+// splitResponse splits content of response file into arguments. Whitespace separates arguments unless quoted.
+// Single quotes keep everything as is, double quotes and unquoted text allow backslash to escape the next character
+func splitResponse(content string) ([]string, error) {
+	result := make([]string, 0)
+	var token []byte
+	inToken := false
+	var quote byte
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				token = append(token, c)
+			}
+		case c == '\\':
+			if i+1 < len(content) {
+				i++
+				token = append(token, content[i])
+			}
+			inToken = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				token = append(token, c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inToken = true
+		case strings.IndexByte(" \t\r\n", c) >= 0:
+			if inToken {
+				result = append(result, string(token))
+				token = token[:0]
+				inToken = false
+			}
+		default:
+			token = append(token, c)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, newParseError(KindBadValue, "has unterminated %c quote", quote)
+	}
+	if inToken {
+		result = append(result, string(token))
+	}
+	return result, nil
+}